@@ -0,0 +1,101 @@
+// Package apierror is the API's error model. Handlers report failures as
+// *Error values, and Middleware renders them as RFC 7807 problem details with
+// a stable, machine-readable code.
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Codes shared across the API. Handlers define codes of their own for
+// failures specific to them. Codes are part of the API and must not change.
+const (
+	CodeInvalidBody      = "invalid_body"
+	CodeValidationFailed = "validation_failed"
+	CodeInvalidID        = "invalid_id"
+	CodeInvalidQuery     = "invalid_query"
+	CodeUnauthenticated  = "unauthenticated"
+	CodeForbidden        = "forbidden"
+	CodeRouteNotFound    = "route_not_found"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+	CodeTimeout          = "timeout"
+	CodeCancelled        = "request_cancelled"
+)
+
+// Error is a failure reported to the client. Err, the underlying cause, is
+// logged but never sent.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+// FieldError describes one invalid field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// New returns an Error for an expected failure such as a missing document.
+func New(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// Internal wraps an unexpected err. Only detail reaches the client.
+func Internal(err error, detail string) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Detail: detail, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Detail + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// From converts any error into an Error. Errors caused by the request's
+// deadline or cancellation become 504 and 503, whatever they were wrapped
+// in; anything else that is not an Error is internal.
+func From(err error) *Error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Status: http.StatusGatewayTimeout, Code: CodeTimeout, Detail: "The request took too long, please try again later.", Err: err}
+	case errors.Is(err, context.Canceled):
+		return &Error{Status: http.StatusServiceUnavailable, Code: CodeCancelled, Detail: "The request was cancelled, please try again.", Err: err}
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return Internal(err, "Something went wrong, please try again later.")
+}
+
+// Abort records err for Middleware to render and stops the handler chain.
+// Handlers return right after calling it.
+func Abort(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// Recovery turns a panic into an internal error for Middleware to render and
+// log, in place of gin.Recovery's plain-text output.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		Abort(c, Internal(fmt.Errorf("panic: %v", recovered), "Something went wrong, please try again later."))
+	})
+}
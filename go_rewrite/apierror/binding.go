@@ -0,0 +1,123 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// UseJSONFieldNames makes validation errors name fields as they appear in
+// request bodies rather than by their Go names.
+func UseJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+}
+
+// FromBinding translates an error from c.ShouldBindJSON. Validation failures
+// become 422 with one FieldError per invalid field; bodies that are not JSON
+// at all are 400.
+func FromBinding(err error) *Error {
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		fields := make([]FieldError, 0, len(invalid))
+		for _, fe := range invalid {
+			fields = append(fields, FieldError{Field: fieldPath(fe), Code: fe.Tag(), Message: fieldMessage(fe)})
+		}
+		return &Error{
+			Status: http.StatusUnprocessableEntity,
+			Code:   CodeValidationFailed,
+			Detail: "Invalid inputs passed, please check your data.",
+			Fields: fields,
+			Err:    err,
+		}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &Error{
+			Status: http.StatusUnprocessableEntity,
+			Code:   CodeValidationFailed,
+			Detail: "Invalid inputs passed, please check your data.",
+			Fields: []FieldError{{Field: typeErr.Field, Code: "type", Message: "must be " + typeName(typeErr.Type)}},
+			Err:    err,
+		}
+	}
+
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidBody, Detail: "The request body could not be read as JSON.", Err: err}
+}
+
+// fieldPath drops the struct name validator puts in front, leaving paths
+// such as tags[2].
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	counted := fe.Kind() == reflect.String || fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map
+	unit := "items"
+	if fe.Kind() == reflect.String {
+		unit = "characters"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min":
+		if counted {
+			return fmt.Sprintf("must have at least %s %s", fe.Param(), unit)
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if counted {
+			return fmt.Sprintf("must have at most %s %s", fe.Param(), unit)
+		}
+		return "must be at most " + fe.Param()
+	default:
+		return fmt.Sprintf("failed the %s check", fe.Tag())
+	}
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "a " + t.String()
+	}
+}
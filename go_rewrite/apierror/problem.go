@@ -0,0 +1,73 @@
+package apierror
+
+import (
+	"net/http"
+
+	"backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem details, from RFC 7807.
+const ContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body of every error response. Code, RequestID,
+// Message and Errors are extension members; Message repeats Detail for
+// clients written against the earlier {"message": ...} errors.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"requestId,omitempty"`
+	Message   string       `json:"message"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Middleware renders the last error a handler recorded with Abort or
+// c.Error, unless a response was written already. It must run after
+// logging.RequestID.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		Write(c, c.Errors.Last().Err)
+	}
+}
+
+// Write logs err as its status deserves and responds with its problem
+// details.
+func Write(c *gin.Context, err error) {
+	e := From(err)
+
+	logger := logging.FromContext(c)
+	switch {
+	case e.Code == CodeCancelled:
+		logger.Info("request cancelled", "error", e.Err)
+	case e.Code == CodeTimeout:
+		logger.Warn("request deadline exceeded", "error", e.Err)
+	case e.Status >= 500:
+		logger.Error(e.Detail, "code", e.Code, "error", e.Err)
+	}
+
+	problem := Problem{
+		// Codes identify problem types, so no type URI is needed.
+		Type:      "about:blank",
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Detail,
+		Instance:  c.Request.URL.Path,
+		Code:      e.Code,
+		RequestID: logging.RequestIDFrom(c),
+		Message:   e.Detail,
+		Errors:    e.Fields,
+	}
+	// c.JSON keeps a Content-Type that is already set.
+	c.Header("Content-Type", ContentType)
+	c.JSON(e.Status, problem)
+}
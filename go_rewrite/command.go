@@ -0,0 +1,27 @@
+package main
+
+import (
+	"backend/config"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// openDatabase connects to the configured database for the subcommands, which
+// read the config file and environment but not the server's flags.
+func openDatabase() (*mongo.Database, error) {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DatabaseURI == "" {
+		return nil, errors.New("config: database_uri (env db) must be set")
+	}
+
+	db, err := config.ConnectDB(cfg.DatabaseURI, cfg.DBTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("Could not connect to database: %w", err)
+	}
+	return db, nil
+}
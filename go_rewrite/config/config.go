@@ -0,0 +1,422 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/keyring"
+	"backend/ratelimit"
+
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting a deployment can tune. Each field is read from,
+// in increasing order of precedence:
+//
+//  1. its default,
+//  2. the YAML or TOML file named by --config or CONFIG_FILE,
+//  3. its environment variable,
+//  4. its command-line flag.
+//
+// The key tag names the field in files; flags use the same name with dashes.
+// Secrets have no flag, since flags are visible to other local users.
+type Config struct {
+	Port        int           `key:"port" env:"PORT" default:"5000" usage:"HTTP port to listen on"`
+	DatabaseURI string        `key:"database_uri" env:"db" flag:"-" usage:"MongoDB connection string"`
+	DBTimeout   time.Duration `key:"db_timeout" env:"DB_TIMEOUT" default:"10s" usage:"deadline for handling a request, unless route_timeouts sets one, and for each scheduled publishing run"`
+	// RouteTimeouts overrides DBTimeout per route, as "METHOD /route=duration"
+	// or "/route=duration" for every method, with Gin's route templates.
+	RouteTimeouts []string `key:"route_timeouts" env:"ROUTE_TIMEOUTS" usage:"comma-separated per-route deadlines such as 'GET /search=5s'"`
+	AutoMigrate   bool     `key:"auto_migrate" env:"AUTO_MIGRATE" default:"true" usage:"apply pending migrations on start"`
+
+	ConnectRetryFor time.Duration `key:"connect_retry_for" env:"CONNECT_RETRY_FOR" default:"1m" usage:"how long to keep retrying the database connection on start"`
+	ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"30s" usage:"how long in-flight requests may take to finish on shutdown"`
+	ShutdownDelay   time.Duration `key:"shutdown_delay" env:"SHUTDOWN_DELAY" default:"5s" usage:"how long readiness fails before the server stops accepting connections, so load balancers route around it first"`
+
+	LogLevel  string `key:"log_level" env:"LOG_LEVEL" default:"info" usage:"least severe log level written: debug, info, warn or error"`
+	LogFormat string `key:"log_format" env:"LOG_FORMAT" default:"json" usage:"log format: json or text"`
+
+	AccessTokenTTL   time.Duration `key:"access_token_ttl" env:"ACCESS_TOKEN_TTL" default:"1h" usage:"lifetime of access tokens"`
+	RefreshTokenTTL  time.Duration `key:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" default:"720h" usage:"lifetime of refresh tokens"`
+	PasswordResetTTL time.Duration `key:"password_reset_ttl" env:"PASSWORD_RESET_TTL" default:"1h" usage:"lifetime of password reset links"`
+	VerifyEmailTTL   time.Duration `key:"verify_email_ttl" env:"VERIFY_EMAIL_TTL" default:"24h" usage:"lifetime of email verification links"`
+	BcryptCost       int           `key:"bcrypt_cost" env:"BCRYPT_COST" default:"12" usage:"bcrypt cost for password hashes"`
+
+	TwoFactorIssuer       string        `key:"two_factor_issuer" env:"TWO_FACTOR_ISSUER" default:"Blog" usage:"name authenticator apps show for this site"`
+	TwoFactorChallengeTTL time.Duration `key:"two_factor_challenge_ttl" env:"TWO_FACTOR_CHALLENGE_TTL" default:"5m" usage:"how long after the password a 2FA code can be entered"`
+
+	TokenAlgorithm      string        `key:"token_algorithm" env:"TOKEN_ALGORITHM" default:"EdDSA" usage:"algorithm new signing keys use: EdDSA or RS256"`
+	TokenIssuer         string        `key:"token_issuer" env:"TOKEN_ISSUER" default:"backend" usage:"iss claim access tokens are issued with and must carry"`
+	TokenAudience       string        `key:"token_audience" env:"TOKEN_AUDIENCE" default:"backend" usage:"aud claim access tokens are issued with and must carry"`
+	KeyRotationInterval time.Duration `key:"key_rotation_interval" env:"KEY_ROTATION_INTERVAL" default:"720h" usage:"how long each signing key signs before the next takes over; 0 keeps the current key"`
+
+	CORSOrigins []string `key:"cors_origins" env:"CORS_ORIGINS" default:"https://aryan7901.github.io,http://localhost:3000" usage:"comma-separated origins allowed by CORS"`
+	FrontendURL string   `key:"frontend_url" env:"FRONTEND_URL" default:"http://localhost:3000" usage:"base URL for links in emails"`
+
+	SessionCookies bool   `key:"session_cookies" env:"SESSION_COOKIES" default:"false" usage:"keep browser sessions in HttpOnly cookies instead of response bodies, with CSRF protection"`
+	CookieDomain   string `key:"cookie_domain" env:"COOKIE_DOMAIN" usage:"domain session cookies are set for; empty for the API's host only"`
+	CookieSecure   bool   `key:"cookie_secure" env:"COOKIE_SECURE" default:"true" usage:"send session cookies over HTTPS only; disable for local development"`
+	CookieSameSite string `key:"cookie_same_site" env:"COOKIE_SAME_SITE" default:"lax" usage:"SameSite of session cookies: strict, lax, or none when the frontend is on another site"`
+
+	RequireEmailVerification bool          `key:"require_email_verification" env:"REQUIRE_EMAIL_VERIFICATION" default:"false" usage:"stop unverified users from posting"`
+	MaxCommentDepth          int           `key:"max_comment_depth" env:"MAX_COMMENT_DEPTH" default:"5" usage:"how deeply comment replies may nest"`
+	SearchBackend            string        `key:"search_backend" env:"SEARCH_BACKEND" default:"mongo" usage:"search backend: mongo or memory"`
+	PublishInterval          time.Duration `key:"publish_interval" env:"PUBLISH_INTERVAL" default:"30s" usage:"how often scheduled blogs are published"`
+
+	// Rate limits are written as requests/duration, such as 10/1m, or off.
+	RateLimitStore    string          `key:"rate_limit_store" env:"RATE_LIMIT_STORE" default:"memory" usage:"where rate limit counters live: memory, or mongo to share them between instances"`
+	TrustedProxies    []string        `key:"trusted_proxies" env:"TRUSTED_PROXIES" usage:"comma-separated addresses or CIDRs of the reverse proxies whose X-Forwarded-For is believed; by default none are, and clients are limited by their connection's address"`
+	LoginIPLimit      ratelimit.Limit `key:"login_ip_limit" env:"LOGIN_IP_LIMIT" default:"20/1m" usage:"login attempts allowed per client IP"`
+	LoginAccountLimit ratelimit.Limit `key:"login_account_limit" env:"LOGIN_ACCOUNT_LIMIT" default:"10/15m" usage:"login attempts allowed per account"`
+	SignupIPLimit     ratelimit.Limit `key:"signup_ip_limit" env:"SIGNUP_IP_LIMIT" default:"5/1h" usage:"signups allowed per client IP"`
+	CommentLimit      ratelimit.Limit `key:"comment_limit" env:"COMMENT_LIMIT" default:"10/1m" usage:"comments allowed per user"`
+	CommentIPLimit    ratelimit.Limit `key:"comment_ip_limit" env:"COMMENT_IP_LIMIT" default:"30/1m" usage:"comments allowed per client IP"`
+
+	LoginLockoutThreshold int           `key:"login_lockout_threshold" env:"LOGIN_LOCKOUT_THRESHOLD" default:"5" usage:"consecutive failed logins that lock an account; 0 disables lockouts"`
+	LoginLockoutBase      time.Duration `key:"login_lockout_base" env:"LOGIN_LOCKOUT_BASE" default:"1m" usage:"first lockout, doubled with each further failure"`
+	LoginLockoutMax       time.Duration `key:"login_lockout_max" env:"LOGIN_LOCKOUT_MAX" default:"1h" usage:"longest lockout"`
+
+	SMTPAddr     string `key:"smtp_addr" env:"SMTP_ADDR" usage:"SMTP server host:port; emails are only logged when empty"`
+	SMTPFrom     string `key:"smtp_from" env:"SMTP_FROM" usage:"sender address for emails"`
+	SMTPUsername string `key:"smtp_username" env:"SMTP_USERNAME" usage:"SMTP username"`
+	SMTPPassword string `key:"smtp_password" env:"SMTP_PASSWORD" flag:"-" usage:"SMTP password"`
+	MailDir      string `key:"mail_dir" env:"MAIL_DIR" usage:"directory the log mailer writes emails to"`
+}
+
+// Default returns the configuration with every default applied and nothing
+// else, which is what tests start from.
+func Default() *Config {
+	cfg := &Config{}
+	for _, f := range fields(cfg) {
+		if def := f.tag("default"); def != "" {
+			if err := f.set(def); err != nil {
+				panic(fmt.Sprintf("config: bad default for %s: %v", f.key, err))
+			}
+		}
+	}
+	return cfg
+}
+
+// Load builds the configuration from the file, environment and args, which
+// are command-line flags without the program name. It only reports values
+// that cannot be parsed; call Validate before using the result.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	flags := flag.NewFlagSet("backend", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML file to read settings from")
+	// Flags are collected first and applied last, so that they override
+	// the file and the environment.
+	var flagValues []func() error
+	for _, f := range fields(cfg) {
+		if f.tag("flag") == "-" {
+			continue
+		}
+		name := strings.ReplaceAll(f.key, "_", "-")
+		flags.Func(name, f.usage(), func(value string) error {
+			flagValues = append(flagValues, func() error {
+				if err := f.set(value); err != nil {
+					return fmt.Errorf("config: --%s: %w", name, err)
+				}
+				return nil
+			})
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fields(cfg) {
+		if value, ok := os.LookupEnv(f.tag("env")); ok {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: %w", f.tag("env"), err))
+			}
+		}
+	}
+	for _, apply := range flagValues {
+		if err := apply(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config: %s: unsupported file type, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	byKey := map[string]field{}
+	for _, f := range fields(cfg) {
+		byKey[f.key] = f
+	}
+
+	var errs []error
+	for key, value := range values {
+		f, ok := byKey[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config: %s: unknown setting %q", path, key))
+			continue
+		}
+		if err := f.set(fileValue(value)); err != nil {
+			errs = append(errs, fmt.Errorf("config: %s: %s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fileValue renders a decoded file value in the same syntax the environment
+// uses, so that both go through one parser.
+func fileValue(value any) string {
+	if list, ok := value.([]any); ok {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// Validate reports every setting that is missing or out of range.
+func (cfg *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("config: "+format, args...))
+		}
+	}
+
+	check(cfg.Port > 0 && cfg.Port < 65536, "port must be between 1 and 65535, got %d", cfg.Port)
+	check(cfg.DatabaseURI != "", "database_uri (env db) must be set")
+	check(cfg.DBTimeout > 0, "db_timeout must be positive")
+	for _, entry := range cfg.RouteTimeouts {
+		_, _, d, err := parseRouteTimeout(entry)
+		check(err == nil && d > 0, "route_timeouts: %q is not METHOD /route=duration with a positive duration", entry)
+	}
+	check(cfg.ConnectRetryFor >= 0, "connect_retry_for must not be negative")
+	check(cfg.ShutdownTimeout > 0, "shutdown_timeout must be positive")
+	check(cfg.ShutdownDelay >= 0, "shutdown_delay must not be negative")
+	check(slices.Contains([]string{"debug", "info", "warn", "error"}, cfg.LogLevel),
+		"log_level must be debug, info, warn or error, got %q", cfg.LogLevel)
+	check(cfg.LogFormat == "json" || cfg.LogFormat == "text", "log_format must be json or text, got %q", cfg.LogFormat)
+	check(cfg.TwoFactorIssuer != "", "two_factor_issuer must be set")
+	check(cfg.TwoFactorChallengeTTL > 0, "two_factor_challenge_ttl must be positive")
+	check(slices.Contains(keyring.Algorithms, cfg.TokenAlgorithm),
+		"token_algorithm must be one of %s, got %q", strings.Join(keyring.Algorithms, ", "), cfg.TokenAlgorithm)
+	check(cfg.TokenIssuer != "", "token_issuer must be set")
+	check(cfg.TokenAudience != "", "token_audience must be set")
+	check(cfg.KeyRotationInterval == 0 || cfg.KeyRotationInterval > keyring.PublishAhead,
+		"key_rotation_interval must be 0 or longer than %s", keyring.PublishAhead)
+	check(cfg.AccessTokenTTL > 0, "access_token_ttl must be positive")
+	check(cfg.RefreshTokenTTL >= cfg.AccessTokenTTL, "refresh_token_ttl must not be shorter than access_token_ttl")
+	check(cfg.PasswordResetTTL > 0, "password_reset_ttl must be positive")
+	check(cfg.VerifyEmailTTL > 0, "verify_email_ttl must be positive")
+	check(cfg.BcryptCost >= bcrypt.MinCost && cfg.BcryptCost <= bcrypt.MaxCost,
+		"bcrypt_cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cfg.BcryptCost)
+	check(len(cfg.CORSOrigins) > 0, "cors_origins must list at least one origin")
+	for _, origin := range cfg.CORSOrigins {
+		check(origin == "*" || isURL(origin), "cors_origins: %q is not an origin", origin)
+	}
+	check(isURL(cfg.FrontendURL), "frontend_url: %q is not a URL", cfg.FrontendURL)
+	check(slices.Contains([]string{"strict", "lax", "none"}, cfg.CookieSameSite),
+		"cookie_same_site must be strict, lax or none, got %q", cfg.CookieSameSite)
+	check(cfg.CookieSameSite != "none" || cfg.CookieSecure, "cookie_same_site none requires cookie_secure")
+	check(cfg.MaxCommentDepth >= 0, "max_comment_depth must not be negative")
+	check(cfg.SearchBackend == "mongo" || cfg.SearchBackend == "memory",
+		"search_backend must be mongo or memory, got %q", cfg.SearchBackend)
+	check(cfg.PublishInterval > 0, "publish_interval must be positive")
+	check(cfg.RateLimitStore == "memory" || cfg.RateLimitStore == "mongo",
+		"rate_limit_store must be memory or mongo, got %q", cfg.RateLimitStore)
+	for _, proxy := range cfg.TrustedProxies {
+		check(isIPOrCIDR(proxy), "trusted_proxies: %q is not an IP address or CIDR", proxy)
+	}
+	check(cfg.LoginLockoutThreshold >= 0, "login_lockout_threshold must not be negative")
+	if cfg.LoginLockoutThreshold > 0 {
+		check(cfg.LoginLockoutBase > 0, "login_lockout_base must be positive")
+		check(cfg.LoginLockoutMax >= cfg.LoginLockoutBase, "login_lockout_max must not be shorter than login_lockout_base")
+	}
+
+	return errors.Join(errs...)
+}
+
+// RouteTimeout returns the deadline for handling a request to route, Gin's
+// template for the matched path.
+func (cfg *Config) RouteTimeout(method, route string) time.Duration {
+	timeout := cfg.DBTimeout
+	for _, entry := range cfg.RouteTimeouts {
+		m, r, d, err := parseRouteTimeout(entry)
+		if err != nil || r != route {
+			continue
+		}
+		if m == method {
+			return d
+		}
+		if m == "" {
+			timeout = d
+		}
+	}
+	return timeout
+}
+
+func parseRouteTimeout(entry string) (method, route string, timeout time.Duration, err error) {
+	target, value, ok := strings.Cut(entry, "=")
+	if !ok {
+		return "", "", 0, errors.New("missing =")
+	}
+	fields := strings.Fields(target)
+	switch len(fields) {
+	case 1:
+		route = fields[0]
+	case 2:
+		method, route = strings.ToUpper(fields[0]), fields[1]
+	default:
+		return "", "", 0, errors.New("expected METHOD /route")
+	}
+	if !strings.HasPrefix(route, "/") {
+		return "", "", 0, errors.New("route must start with /")
+	}
+	timeout, err = time.ParseDuration(strings.TrimSpace(value))
+	return method, route, timeout, err
+}
+
+// KeyPolicy returns how the keyring creates and keeps signing keys. Replaced
+// keys must outlive both access tokens and 2FA challenges, which are signed
+// with the same keys.
+func (cfg *Config) KeyPolicy() keyring.Policy {
+	return keyring.Policy{
+		Algorithm:   cfg.TokenAlgorithm,
+		RotateEvery: cfg.KeyRotationInterval,
+		TokenTTL:    max(cfg.AccessTokenTTL, cfg.TwoFactorChallengeTTL),
+	}
+}
+
+// LoginLockout returns the lockout policy for failed logins.
+func (cfg *Config) LoginLockout() ratelimit.Lockout {
+	return ratelimit.Lockout{
+		Threshold: cfg.LoginLockoutThreshold,
+		Base:      cfg.LoginLockoutBase,
+		Max:       cfg.LoginLockoutMax,
+	}
+}
+
+func isIPOrCIDR(s string) bool {
+	if _, err := netip.ParsePrefix(s); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// field is one setting of a Config, addressed through its struct tags.
+type field struct {
+	key   string
+	value reflect.Value
+	tags  reflect.StructTag
+}
+
+func fields(cfg *Config) []field {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	result := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tags := t.Field(i).Tag
+		result = append(result, field{key: tags.Get("key"), value: v.Field(i), tags: tags})
+	}
+	return result
+}
+
+func (f field) tag(name string) string {
+	return f.tags.Get(name)
+}
+
+func (f field) usage() string {
+	usage := f.tag("usage")
+	if env := f.tag("env"); env != "" {
+		usage += " (env " + env + ")"
+	}
+	return usage
+}
+
+func (f field) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if u, ok := f.value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(raw)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		f.value.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		f.value.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 1h", raw)
+		}
+		f.value.SetInt(int64(d))
+	case []string:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.value.Set(reflect.ValueOf(items))
+	default:
+		panic("config: unsupported field type " + f.value.Type().String())
+	}
+	return nil
+}
@@ -2,19 +2,16 @@ package config
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
+	"backend/metrics"
+
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var DB *mongo.Client
-var UserCollection *mongo.Collection
-var BlogCollection *mongo.Collection
-var CommentCollection *mongo.Collection
-
 func getDatabaseName(uri string) string {
 	if idx := strings.LastIndex(uri, "/"); idx != -1 && idx+1 < len(uri) {
 		remaining := uri[idx+1:]
@@ -28,30 +25,49 @@ func getDatabaseName(uri string) string {
 	return "myblog"
 }
 
-func ConnectDB(uri string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// ConnectDB connects to uri and pings it, giving up after timeout.
+func ConnectDB(uri string, timeout time.Duration) (*mongo.Database, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	clientOptions := options.Client().ApplyURI(uri)
+	clientOptions := options.Client().ApplyURI(uri).SetMonitor(metrics.CommandMonitor())
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = client.Ping(ctx, nil)
 	if err != nil {
-		return err
+		client.Disconnect(context.Background())
+		return nil, err
 	}
 
-	DB = client
 	database := getDatabaseName(uri)
-	UserCollection = DB.Database(database).Collection("users")
-	BlogCollection = DB.Database(database).Collection("blogs")
-	CommentCollection = DB.Database(database).Collection("comments")
-	log.Printf("Connected to database: %s", database)
-	return nil
+	slog.Info("Connected to database", "database", database)
+	return client.Database(database), nil
 }
 
-func GetCollection(database, collection string) *mongo.Collection {
-	return DB.Database(database).Collection(collection)
+// ConnectDBRetrying calls ConnectDB until it succeeds, retryFor has passed or
+// ctx is cancelled, backing off between attempts. This lets the server start
+// alongside its database instead of failing on the first attempt.
+func ConnectDBRetrying(ctx context.Context, uri string, timeout, retryFor time.Duration) (*mongo.Database, error) {
+	deadline := time.Now().Add(retryFor)
+	delay := time.Second
+	for {
+		db, err := ConnectDB(uri, timeout)
+		if err == nil {
+			return db, nil
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return nil, err
+		}
+
+		slog.Warn("Could not connect to database, retrying", "retry_in", delay.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, 10*time.Second)
+	}
 }
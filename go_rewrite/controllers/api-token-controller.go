@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"backend/apierror"
+	"backend/models"
+	"backend/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxAPITokens = 20
+	// apiTokenHintLength is how much of a token is kept to tell it apart,
+	// which is far too little to guess the rest from.
+	apiTokenHintLength = len(models.APITokenPrefix) + 4
+)
+
+// CreateAPIToken issues a personal access token for scripts. The response is
+// the only time its plaintext is shown.
+func (h *Handler) CreateAPIToken(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	var createReq models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&createReq); err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return
+	}
+	if createReq.ExpiresAt != nil && !createReq.ExpiresAt.After(time.Now()) {
+		apierror.Abort(c, errInvalidExpiresAt)
+		return
+	}
+
+	userData := c.MustGet("userData").(map[string]string)
+	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+
+	count, err := h.APITokens.CountByUser(ctx, uid)
+	if err != nil {
+		serverError(c, err, "Creating API token failed, please try again later.")
+		return
+	}
+	if count >= maxAPITokens {
+		apierror.Abort(c, errTooManyAPITokens)
+		return
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		serverError(c, err, "Creating API token failed, please try again later.")
+		return
+	}
+	plain := models.APITokenPrefix + secret
+
+	scopes := slices.Clone(createReq.Scopes)
+	slices.Sort(scopes)
+	token := models.APIToken{
+		User:      uid,
+		Name:      createReq.Name,
+		Scopes:    slices.Compact(scopes),
+		TokenHash: models.HashAPIToken(plain),
+		Hint:      plain[:apiTokenHintLength],
+		CreatedAt: time.Now(),
+		ExpiresAt: createReq.ExpiresAt,
+	}
+	err = h.APITokens.Create(ctx, &token)
+	if err != nil {
+		serverError(c, err, "Creating API token failed, please try again later.")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(201, models.CreateAPITokenResponse{APIToken: token, Token: plain})
+}
+
+func (h *Handler) ListAPITokens(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	userData := c.MustGet("userData").(map[string]string)
+	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+
+	tokens, err := h.APITokens.ListByUser(ctx, uid)
+	if err != nil {
+		serverError(c, err, "Fetching API tokens failed, please try again later.")
+		return
+	}
+
+	c.JSON(200, models.APITokenListResponse{Tokens: tokens})
+}
+
+func (h *Handler) RevokeAPIToken(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	tid, err := primitive.ObjectIDFromHex(c.Param("tid"))
+	if err != nil {
+		apierror.Abort(c, invalidID("API token"))
+		return
+	}
+
+	userData := c.MustGet("userData").(map[string]string)
+	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+
+	err = h.APITokens.Delete(ctx, tid, uid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errAPITokenNotFound)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Revoking API token failed, please try again later.")
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "API token revoked!"})
+}
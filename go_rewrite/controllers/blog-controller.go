@@ -2,155 +2,229 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
-	"backend/config"
+	"backend/apierror"
+	"backend/logging"
+	"backend/metrics"
 	"backend/models"
+	"backend/policy"
+	"backend/slug"
+	"backend/store"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func GetAllBlogs(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// authorMap loads the users referenced by ids, keyed by their ObjectID.
+func (h *Handler) authorMap(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]models.User, error) {
+	userMap := make(map[primitive.ObjectID]models.User)
+	if len(ids) == 0 {
+		return userMap, nil
+	}
+
+	users, err := h.Users.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		userMap[u.ID] = u
+	}
+	return userMap, nil
+}
+
+// listBlogs loads a page of the blogs matching query together with their
+// authors' names, and their total count when withTotal is set.
+func (h *Handler) listBlogs(ctx context.Context, query store.BlogQuery, withTotal bool) (*models.BlogListResponse, error) {
+	page, err := h.Blogs.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	blogs := page.Blogs
+
+	authorIDs := make([]primitive.ObjectID, 0, len(blogs))
+	for _, blog := range blogs {
+		authorIDs = append(authorIDs, blog.Author)
+	}
+	authors, err := h.authorMap(ctx, authorIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var blogResponses []models.BlogResponse = make([]models.BlogResponse, 0, len(blogs))
+	for _, blog := range blogs {
+		author := authors[blog.Author]
+		blogResponses = append(blogResponses, models.BlogResponse{
+			ID:          blog.ID.Hex(),
+			Title:       blog.Title,
+			Slug:        blog.Slug,
+			Author:      models.Author{FirstName: author.FirstName, LastName: author.LastName},
+			Description: blog.Description,
+			Article:     blog.Article,
+			Tags:        blog.Tags,
+			Status:      blog.CurrentStatus(),
+			PublishedAt: blog.PublishedAt,
+		})
+	}
+
+	response := &models.BlogListResponse{
+		Blogs:      blogResponses,
+		NextCursor: encodeCursor(page.Next),
+		PrevCursor: encodeCursor(page.Prev),
+	}
+	if withTotal {
+		total, err := h.Blogs.Count(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		response.Total = &total
+	}
+	return response, nil
+}
+
+func (h *Handler) GetAllBlogs(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
-	pipeline := []bson.M{
-		{
-			"$lookup": bson.M{
-				"from":         "users",
-				"localField":   "author",
-				"foreignField": "_id",
-				"as":           "authorData",
-			},
-		},
-		{
-			"$unwind": bson.M{
-				"path":                       "$authorData",
-				"preserveNullAndEmptyArrays": true,
-			},
-		},
-		{
-			"$project": bson.M{
-				"_id":         1,
-				"title":       1,
-				"description": 1,
-				"article":     1,
-				"author": bson.M{
-					"firstName": "$authorData.firstName",
-					"lastName":  "$authorData.lastName",
-				},
-			},
-		},
-	}
-
-	cursor, err := config.BlogCollection.Aggregate(ctx, pipeline)
+	query, withTotal, err := parseBlogQuery(c)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Error Retrieving data, please try again later."})
+		apierror.Abort(c, err)
 		return
 	}
-	defer cursor.Close(ctx)
 
-	var blogResponses []models.BlogResponse = make([]models.BlogResponse, 0)
-	if err := cursor.All(ctx, &blogResponses); err != nil {
-		c.JSON(500, gin.H{"message": "Error Retrieving data, please try again later."})
+	query.VisibleTo = actorFromContext(c).ID
+	if rawTag := c.Query("tag"); rawTag != "" {
+		query.Tag = slug.Tag(rawTag)
+		// No blog can carry a tag that normalizes to nothing.
+		if query.Tag == "" {
+			response := models.BlogListResponse{Blogs: []models.BlogResponse{}}
+			if withTotal {
+				response.Total = new(int64)
+			}
+			c.JSON(200, response)
+			return
+		}
+	}
+
+	response, err := h.listBlogs(ctx, query, withTotal)
+	if err != nil {
+		serverError(c, err, "Error Retrieving data, please try again later.")
 		return
 	}
 
-	c.JSON(200, gin.H{"blogs": blogResponses})
+	c.JSON(200, response)
 }
 
-func GetBlogById(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) GetBlogById(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
 	blogId := c.Param("bid")
 	oid, err := primitive.ObjectIDFromHex(blogId)
 	if err != nil {
-		c.JSON(400, gin.H{"message": "Invalid blog ID"})
+		apierror.Abort(c, invalidID("blog"))
 		return
 	}
 
-	var blog models.Blog
-	err = config.BlogCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&blog)
+	blog, err := h.Blogs.FindByID(ctx, oid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errBlogNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Error Retrieving blog, please try again later."})
+		serverError(c, err, "Error Retrieving blog, please try again later.")
 		return
 	}
 
-	var author models.User
-	_ = config.UserCollection.FindOne(ctx, bson.M{"_id": blog.Author}).Decode(&author)
-
-	comments := make([]models.CommentResponse, 0)
-	if len(blog.Comments) > 0 {
-		commentCursor, err := config.CommentCollection.Find(ctx, bson.M{"_id": bson.M{"$in": blog.Comments}})
-		if err == nil {
-			var commentDocs []models.Comment
-			commentCursor.All(ctx, &commentDocs)
-
-			userIDs := make([]primitive.ObjectID, 0, len(commentDocs))
-			for _, c := range commentDocs {
-				userIDs = append(userIDs, c.User)
-			}
+	if !canView(actorFromContext(c), blog) {
+		apierror.Abort(c, errBlogNotFound)
+		return
+	}
 
-			var users []models.User
-			if len(userIDs) > 0 {
-				userCursor, _ := config.UserCollection.Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}})
-				userCursor.All(ctx, &users)
-			}
+	h.renderBlog(ctx, c, blog)
+}
 
-			userMap := make(map[primitive.ObjectID]models.User)
-			for _, u := range users {
-				userMap[u.ID] = u
-			}
+// renderBlog responds with blog, its author and a page of its comment
+// threads, selected by the threadLimit and threadOffset query parameters.
+// format=flat lists the threads depth-first instead of nesting replies.
+func (h *Handler) renderBlog(ctx context.Context, c *gin.Context, blog *models.Blog) {
+	limit, offset, err := parsePage(c, "threadLimit", "threadOffset", threadPageSize)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
 
-			for _, comment := range commentDocs {
-				user := userMap[comment.User]
-				comments = append(comments, models.CommentResponse{
-					ID:      comment.ID.Hex(),
-					Content: comment.Content,
-					Date:    comment.Date,
-					User:    models.Author{FirstName: user.FirstName, LastName: user.LastName},
-				})
-			}
-		}
+	// A missing author still leaves the blog worth reading.
+	var author models.User
+	found, err := h.Users.FindByID(ctx, blog.Author)
+	switch {
+	case err == nil:
+		author = *found
+	case errors.Is(err, store.ErrNotFound):
+		logging.FromContext(c).Warn("blog author not found", "blog_id", blog.ID.Hex(), "author_id", blog.Author.Hex())
+	default:
+		logging.FromContext(c).Error("loading blog author failed", "blog_id", blog.ID.Hex(), "author_id", blog.Author.Hex(), "error", err)
+	}
+
+	comments, threads, err := h.loadComments(ctx, blog, primitive.NilObjectID, offset, limit)
+	if err != nil {
+		serverError(c, err, "Fetching blog failed, please try again later.")
+		return
+	}
+	if c.Query("format") == "flat" {
+		comments = flatten(comments)
 	}
 
 	c.JSON(200, gin.H{"blog": models.BlogResponse{
 		ID:          blog.ID.Hex(),
 		Title:       blog.Title,
+		Slug:        blog.Slug,
 		Author:      models.Author{FirstName: author.FirstName, LastName: author.LastName},
 		Description: blog.Description,
 		Article:     blog.Article,
 		Comments:    comments,
+		ThreadCount: threads,
+		Tags:        blog.Tags,
+		Status:      blog.CurrentStatus(),
+		PublishedAt: blog.PublishedAt,
 	}})
 }
 
-func MakeComment(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) MakeComment(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
 	blogId := c.Param("bid")
 	bid, err := primitive.ObjectIDFromHex(blogId)
 	if err != nil {
-		c.JSON(400, gin.H{"message": "Invalid blog ID"})
+		apierror.Abort(c, invalidID("blog"))
 		return
 	}
 
 	var commentReq models.CommentRequest
 	if err := c.ShouldBindJSON(&commentReq); err != nil {
-		c.JSON(422, gin.H{"message": "Invalid inputs passed, please check your data."})
+		apierror.Abort(c, apierror.FromBinding(err))
 		return
 	}
 
 	userData := c.MustGet("userData").(map[string]string)
 	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
 
-	var blog models.Blog
-	err = config.BlogCollection.FindOne(ctx, bson.M{"_id": bid}).Decode(&blog)
+	blog, err := h.Blogs.FindByID(ctx, bid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errBlogNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Could not find blog, please try again later."})
+		serverError(c, err, "Adding comment failed, please try again later.")
+		return
+	}
+
+	if !canView(policy.ActorFromUserData(userData), blog) {
+		apierror.Abort(c, errBlogNotFound)
 		return
 	}
 
@@ -161,100 +235,140 @@ func MakeComment(c *gin.Context) {
 		Blog:    bid,
 	}
 
-	result, err := config.CommentCollection.InsertOne(ctx, comment)
-	if err != nil {
-		c.JSON(500, gin.H{"message": "Adding comment failed, please try again later."})
-		return
+	if commentReq.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(commentReq.ParentID)
+		if err != nil {
+			apierror.Abort(c, invalidID("parent comment"))
+			return
+		}
+
+		parent, err := h.Comments.FindByID(ctx, parentID)
+		if errors.Is(err, store.ErrNotFound) || (err == nil && parent.Blog != bid) {
+			apierror.Abort(c, errParentNotFound)
+			return
+		}
+		if err != nil {
+			serverError(c, err, "Adding comment failed, please try again later.")
+			return
+		}
+		if parent.Deleted {
+			apierror.Abort(c, apierror.New(http.StatusUnprocessableEntity, codeCommentDeleted, "Cannot reply to a deleted comment."))
+			return
+		}
+		if parent.Depth+1 > h.Config.MaxCommentDepth {
+			apierror.Abort(c, apierror.New(http.StatusUnprocessableEntity, codeReplyTooDeep,
+				fmt.Sprintf("Replies cannot be nested more than %d levels deep.", h.Config.MaxCommentDepth)))
+			return
+		}
+
+		comment.ParentID = parent.ID
+		comment.Depth = parent.Depth + 1
 	}
 
-	cid := result.InsertedID.(primitive.ObjectID)
-	_, err = config.BlogCollection.UpdateOne(ctx, bson.M{"_id": bid}, bson.M{"$push": bson.M{"comments": cid}})
+	err = h.createComment(ctx, &comment)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Adding comment failed, please try again later."})
+		serverError(c, err, "Adding comment failed, please try again later.")
 		return
 	}
+	metrics.CommentsPosted.Inc()
+	h.indexComment(ctx, c, &comment)
 
 	c.JSON(201, gin.H{"message": "Comment Created!"})
 }
 
-func UpdateComment(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) UpdateComment(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
 	commentId := c.Param("cid")
 	cid, err := primitive.ObjectIDFromHex(commentId)
 	if err != nil {
-		c.JSON(400, gin.H{"message": "Invalid comment ID"})
+		apierror.Abort(c, invalidID("comment"))
 		return
 	}
 
 	var commentReq models.CommentRequest
 	if err := c.ShouldBindJSON(&commentReq); err != nil {
-		c.JSON(422, gin.H{"message": "Invalid inputs passed, please check your data."})
+		apierror.Abort(c, apierror.FromBinding(err))
 		return
 	}
 
-	userData := c.MustGet("userData").(map[string]string)
-	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+	actor := policy.ActorFromUserData(c.MustGet("userData").(map[string]string))
 
-	var comment models.Comment
-	err = config.CommentCollection.FindOne(ctx, bson.M{"_id": cid}).Decode(&comment)
+	comment, err := h.Comments.FindByID(ctx, cid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errCommentNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Updating comment failed, please try again later."})
+		serverError(c, err, "Updating comment failed, please try again later.")
 		return
 	}
 
-	if comment.User != uid {
-		c.JSON(401, gin.H{"message": "Unauthorized!"})
+	if comment.Deleted {
+		apierror.Abort(c, errCommentNotFound)
 		return
 	}
 
-	_, err = config.CommentCollection.UpdateOne(ctx, bson.M{"_id": cid}, bson.M{"$set": bson.M{"content": commentReq.Comment}})
+	if !policy.Can(actor, policy.UpdateComment, comment.User) {
+		apierror.Abort(c, errNotAllowed)
+		return
+	}
+
+	err = h.Comments.UpdateContent(ctx, cid, commentReq.Comment)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Updating comment failed, please try again later."})
+		serverError(c, err, "Updating comment failed, please try again later.")
 		return
 	}
+	comment.Content = commentReq.Comment
+	h.indexComment(ctx, c, comment)
 
 	c.JSON(200, gin.H{"message": "Comment updated!"})
 }
 
-func DeleteComment(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) DeleteComment(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
 	commentId := c.Param("cid")
 	cid, err := primitive.ObjectIDFromHex(commentId)
 	if err != nil {
-		c.JSON(400, gin.H{"message": "Invalid comment ID"})
+		apierror.Abort(c, invalidID("comment"))
 		return
 	}
 
-	userData := c.MustGet("userData").(map[string]string)
-	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+	actor := policy.ActorFromUserData(c.MustGet("userData").(map[string]string))
 
-	var comment models.Comment
-	err = config.CommentCollection.FindOne(ctx, bson.M{"_id": cid}).Decode(&comment)
+	comment, err := h.Comments.FindByID(ctx, cid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errCommentNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Deleting comment failed, please try again later."})
+		serverError(c, err, "Deleting comment failed, please try again later.")
 		return
 	}
 
-	if comment.User != uid {
-		c.JSON(401, gin.H{"message": "Unauthorized!"})
+	if comment.Deleted {
+		apierror.Abort(c, errCommentNotFound)
 		return
 	}
 
-	_, err = config.CommentCollection.DeleteOne(ctx, bson.M{"_id": cid})
-	if err != nil {
-		c.JSON(500, gin.H{"message": "Deleting comment failed, please try again later."})
+	if !policy.Can(actor, policy.DeleteComment, comment.User) {
+		apierror.Abort(c, errNotAllowed)
 		return
 	}
 
-	_, err = config.BlogCollection.UpdateOne(ctx, bson.M{"comments": cid}, bson.M{"$pull": bson.M{"comments": cid}})
+	// A comment with replies stays behind as a placeholder so the replies
+	// keep their place in the thread.
+	err = h.deleteComment(ctx, comment)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Deleting comment failed, please try again later."})
+		serverError(c, err, "Deleting comment failed, please try again later.")
 		return
 	}
+	if err := h.Search.RemoveComment(ctx, cid); err != nil {
+		logging.FromContext(c).Error("removing comment from the search index failed", "comment_id", cid.Hex(), "error", err)
+	}
 
 	c.JSON(200, gin.H{"message": "Comment deleted!"})
 }
@@ -1,141 +1,185 @@
 package controllers
 
 import (
-	"context"
-	"time"
+	"errors"
+	"slices"
 
-	"backend/config"
+	"backend/apierror"
+	"backend/logging"
+	"backend/metrics"
 	"backend/models"
+	"backend/policy"
+	"backend/store"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func CreateBlog(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) CreateBlog(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
 	var blogReq models.BlogRequest
 	if err := c.ShouldBindJSON(&blogReq); err != nil {
-		c.JSON(422, gin.H{"message": "Invalid inputs passed, please check your data."})
+		apierror.Abort(c, apierror.FromBinding(err))
 		return
 	}
 
 	userData := c.MustGet("userData").(map[string]string)
 	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
 
+	tags, err := normalizeTags(blogReq.Tags)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
 	blog := models.Blog{
 		Title:       blogReq.Title,
 		Description: blogReq.Description,
 		Article:     blogReq.Article,
 		Author:      uid,
 		Comments:    []primitive.ObjectID{},
+		Tags:        tags,
 	}
 
-	result, err := config.BlogCollection.InsertOne(ctx, blog)
+	status := blogReq.Status
+	if status == "" {
+		status = models.StatusPublished
+	}
+	err = setPublishState(&blog, status, blogReq.PublishAt)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Creating new blog failed, please try again later."})
+		apierror.Abort(c, err)
 		return
 	}
 
-	_, err = config.UserCollection.UpdateOne(ctx, bson.M{"_id": uid}, bson.M{"$push": bson.M{"blogs": result.InsertedID}})
+	err = retryOnSlugTaken(func() error {
+		var err error
+		blog.Slug, err = h.uniqueSlug(ctx, blog.Title, primitive.NilObjectID)
+		if err != nil {
+			return err
+		}
+		return h.createBlog(ctx, &blog)
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Creating new blog failed, please try again later."})
+		serverError(c, err, "Creating new blog failed, please try again later.")
 		return
 	}
+	metrics.BlogsCreated.Inc()
+	h.indexBlog(ctx, c, &blog)
 
 	c.JSON(201, gin.H{"createdBlog": blog})
 }
 
-func UpdateBlog(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) UpdateBlog(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
 	blogId := c.Param("bid")
 	bid, err := primitive.ObjectIDFromHex(blogId)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Invalid blog ID"})
+		apierror.Abort(c, invalidID("blog"))
 		return
 	}
 
 	var blogReq models.BlogRequest
 	if err := c.ShouldBindJSON(&blogReq); err != nil {
-		c.JSON(422, gin.H{"message": "Invalid inputs passed, please check your data."})
+		apierror.Abort(c, apierror.FromBinding(err))
 		return
 	}
 
-	userData := c.MustGet("userData").(map[string]string)
-	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+	actor := policy.ActorFromUserData(c.MustGet("userData").(map[string]string))
 
-	var blog models.Blog
-	err = config.BlogCollection.FindOne(ctx, bson.M{"_id": bid}).Decode(&blog)
+	blog, err := h.Blogs.FindByID(ctx, bid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errBlogNotFound)
+		return
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Updating blog failed, please try again later."})
+		serverError(c, err, "Updating blog failed, please try again later.")
 		return
 	}
 
-	if blog.Author != uid {
-		c.JSON(401, gin.H{"message": "Unauthorized!"})
+	if !policy.Can(actor, policy.UpdateBlog, blog.Author) {
+		apierror.Abort(c, errNotAllowed)
 		return
 	}
 
-	_, err = config.BlogCollection.UpdateOne(ctx, bson.M{"_id": bid}, bson.M{"$set": bson.M{
-		"title":       blogReq.Title,
-		"description": blogReq.Description,
-		"article":     blogReq.Article,
-	}})
+	titleChanged := blog.Title != blogReq.Title
+	blog.Title = blogReq.Title
+	blog.Description = blogReq.Description
+	blog.Article = blogReq.Article
+	if blogReq.Tags != nil {
+		blog.Tags, err = normalizeTags(blogReq.Tags)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+	}
+
+	slug, slugHistory := blog.Slug, blog.SlugHistory
+	err = retryOnSlugTaken(func() error {
+		// Start each attempt from the stored slugs, so a slug lost to a
+		// concurrent write does not end up in the history.
+		blog.Slug, blog.SlugHistory = slug, slices.Clone(slugHistory)
+		if titleChanged || blog.Slug == "" {
+			if err := h.renameSlug(ctx, blog); err != nil {
+				return err
+			}
+		}
+		return h.Blogs.Update(ctx, blog)
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Updating blog failed, please try again later."})
+		serverError(c, err, "Updating blog failed, please try again later.")
 		return
 	}
+	h.indexBlog(ctx, c, blog)
 
 	c.JSON(200, gin.H{"message": "Blog updated!"})
 }
 
-func DeleteBlog(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) DeleteBlog(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
 	blogId := c.Param("bid")
 	bid, err := primitive.ObjectIDFromHex(blogId)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Invalid blog ID"})
+		apierror.Abort(c, invalidID("blog"))
 		return
 	}
 
-	userData := c.MustGet("userData").(map[string]string)
-	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+	actor := policy.ActorFromUserData(c.MustGet("userData").(map[string]string))
 
-	var blog models.Blog
-	err = config.BlogCollection.FindOne(ctx, bson.M{"_id": bid}).Decode(&blog)
-	if err != nil {
-		c.JSON(500, gin.H{"message": "Deleting blog failed, please try again later."})
+	blog, err := h.Blogs.FindByID(ctx, bid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errBlogNotFound)
 		return
 	}
-
-	if blog.Author != uid {
-		c.JSON(401, gin.H{"message": "Unauthorized!"})
+	if err != nil {
+		serverError(c, err, "Deleting blog failed, please try again later.")
 		return
 	}
 
-	_, err = config.BlogCollection.DeleteOne(ctx, bson.M{"_id": bid})
-	if err != nil {
-		c.JSON(500, gin.H{"message": "Deleting blog failed, please try again later."})
+	if !policy.Can(actor, policy.DeleteBlog, blog.Author) {
+		apierror.Abort(c, errNotAllowed)
 		return
 	}
 
-	_, err = config.UserCollection.UpdateOne(ctx, bson.M{"_id": uid}, bson.M{"$pull": bson.M{"blogs": bid}})
+	err = h.deleteBlog(ctx, blog)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Deleting blog failed, please try again later."})
+		serverError(c, err, "Deleting blog failed, please try again later.")
 		return
 	}
+	if err := h.Search.RemoveBlog(ctx, bid); err != nil {
+		logging.FromContext(c).Error("removing blog from the search index failed", "blog_id", bid.Hex(), "error", err)
+	}
 
 	c.JSON(200, gin.H{"message": "Blog deleted!"})
 }
 
-func GetUserBlogs(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) GetUserBlogs(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
 	userData := c.MustGet("userData").(map[string]string)
@@ -143,24 +187,37 @@ func GetUserBlogs(c *gin.Context) {
 	firstName := userData["firstName"]
 	lastName := userData["lastName"]
 
-	cursor, err := config.BlogCollection.Find(ctx, bson.M{"author": uid})
+	query, withTotal, err := parseBlogQuery(c)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "failed to get user's blogs"})
+		apierror.Abort(c, err)
 		return
 	}
-	defer cursor.Close(ctx)
+	query.Author = uid
+	query.VisibleTo = uid
 
-	var blogs []models.Blog = make([]models.Blog, 0)
-	if err := cursor.All(ctx, &blogs); err != nil {
-		c.JSON(500, gin.H{"message": "failed to get user's blogs"})
+	page, err := h.Blogs.List(ctx, query)
+	if err != nil {
+		serverError(c, err, "failed to get user's blogs")
 		return
 	}
 
-	c.JSON(200, models.UserBlogResponse{
-		Blogs: blogs,
+	response := models.UserBlogResponse{
+		Blogs: page.Blogs,
 		Author: models.Author{
 			FirstName: firstName,
 			LastName:  lastName,
 		},
-	})
+		NextCursor: encodeCursor(page.Next),
+		PrevCursor: encodeCursor(page.Prev),
+	}
+	if withTotal {
+		total, err := h.Blogs.Count(ctx, query)
+		if err != nil {
+			serverError(c, err, "failed to get user's blogs")
+			return
+		}
+		response.Total = &total
+	}
+
+	c.JSON(200, response)
 }
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/apierror"
+	"backend/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+const dateOnly = "2006-01-02"
+
+var errInvalidListQuery = apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuery, "Invalid list parameters, please check your query.")
+
+// parseBlogQuery reads the limit, sort, cursor, author, from and to query
+// parameters shared by every blog listing. The limit is clamped to
+// maxPageSize; count=true additionally asks for the total number of matches.
+func parseBlogQuery(c *gin.Context) (query store.BlogQuery, withTotal bool, err error) {
+	query.Limit = defaultPageSize
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return query, false, errInvalidListQuery
+		}
+		query.Limit = min(limit, maxPageSize)
+	}
+
+	if raw := c.Query("sort"); raw != "" {
+		query.Sort = store.BlogSort(raw)
+		if !query.Sort.Valid() {
+			return query, false, errInvalidListQuery
+		}
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := store.DecodeCursor(raw)
+		if err != nil {
+			return query, false, errInvalidListQuery
+		}
+		if query.Sort == "" {
+			query.Sort = cursor.Sort
+		}
+		if cursor.Sort != query.Sort {
+			return query, false, errInvalidListQuery
+		}
+		query.Cursor = cursor
+	}
+
+	if raw := c.Query("author"); raw != "" {
+		query.Author, err = primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return query, false, errInvalidListQuery
+		}
+	}
+
+	if raw := c.Query("from"); raw != "" {
+		query.CreatedFrom, _, err = parseDate(raw)
+		if err != nil {
+			return query, false, errInvalidListQuery
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		to, dayOnly, err := parseDate(raw)
+		if err != nil {
+			return query, false, errInvalidListQuery
+		}
+		// A bare date includes that whole day.
+		if dayOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		query.CreatedTo = to
+	}
+
+	withTotal = c.Query("count") == "true"
+	return query, withTotal, nil
+}
+
+// parseDate accepts either an RFC 3339 timestamp or a plain UTC date.
+func parseDate(raw string) (t time.Time, dayOnly bool, err error) {
+	if t, err = time.Parse(time.RFC3339, raw); err == nil {
+		return t, false, nil
+	}
+	t, err = time.Parse(dateOnly, raw)
+	return t, true, err
+}
+
+func encodeCursor(c *store.Cursor) string {
+	if c == nil {
+		return ""
+	}
+	return c.Encode()
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"backend/apierror"
+	"backend/models"
+	"backend/slug"
+	"backend/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxSlugAttempts bounds how often a write is retried after a concurrent one
+// took the slug uniqueSlug picked.
+const maxSlugAttempts = 5
+
+// reservedSlugs collide with static routes under /blogs and could never be reached.
+var reservedSlugs = []string{"all", "blog", "comment"}
+
+// uniqueSlug derives a slug from title that no other blog uses or has used,
+// suffixing -2, -3, ... on collisions.
+func (h *Handler) uniqueSlug(ctx context.Context, title string, except primitive.ObjectID) (string, error) {
+	base := slug.Make(title)
+	for n := 1; ; n++ {
+		candidate := base
+		if n > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, n)
+		}
+		if slices.Contains(reservedSlugs, candidate) {
+			continue
+		}
+
+		inUse, err := h.Blogs.SlugInUse(ctx, candidate, except)
+		if err != nil {
+			return "", err
+		}
+		if !inUse {
+			return candidate, nil
+		}
+	}
+}
+
+// retryOnSlugTaken runs save, which picks a slug and writes the blog, again
+// while the store rejects the slug as taken. uniqueSlug only checks before the
+// write, so two concurrent writes can pick the same one; the unique index on
+// slug lets the loser pick the next instead.
+func retryOnSlugTaken(save func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := save()
+		if !errors.Is(err, store.ErrDuplicate) || attempt == maxSlugAttempts {
+			return err
+		}
+	}
+}
+
+// renameSlug gives blog a slug for its current title and keeps the previous
+// one in its history so old links still resolve.
+func (h *Handler) renameSlug(ctx context.Context, blog *models.Blog) error {
+	newSlug, err := h.uniqueSlug(ctx, blog.Title, blog.ID)
+	if err != nil {
+		return err
+	}
+	if newSlug == blog.Slug {
+		return nil
+	}
+
+	if blog.Slug != "" && !slices.Contains(blog.SlugHistory, blog.Slug) {
+		blog.SlugHistory = append(blog.SlugHistory, blog.Slug)
+	}
+	blog.SlugHistory = slices.DeleteFunc(blog.SlugHistory, func(s string) bool { return s == newSlug })
+	blog.Slug = newSlug
+	return nil
+}
+
+func (h *Handler) GetBlogBySlug(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	requested := c.Param("slug")
+	blog, err := h.Blogs.FindBySlug(ctx, requested)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errBlogNotFound)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Error Retrieving blog, please try again later.")
+		return
+	}
+
+	if !canView(actorFromContext(c), blog) {
+		apierror.Abort(c, errBlogNotFound)
+		return
+	}
+
+	if blog.Slug != requested {
+		location := "/blogs/" + blog.Slug
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(301, location)
+		return
+	}
+
+	h.renderBlog(ctx, c, blog)
+}
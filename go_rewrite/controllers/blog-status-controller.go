@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"backend/apierror"
+	"backend/models"
+	"backend/policy"
+	"backend/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// canView reports whether actor may read blog in its current state.
+func canView(actor policy.Actor, blog *models.Blog) bool {
+	return blog.IsPublished() || policy.Can(actor, policy.ReadDraft, blog.Author)
+}
+
+// setPublishState moves blog to status. Scheduling needs a publishAt in the
+// future; publishing records the time the blog went live, which publishing
+// an already published blog again leaves alone.
+func setPublishState(blog *models.Blog, status string, publishAt *time.Time) error {
+	now := time.Now()
+
+	switch status {
+	case models.StatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return apierror.New(http.StatusUnprocessableEntity, codeInvalidPublishAt, "Scheduled blogs need a publishAt time in the future.")
+		}
+		blog.PublishAt = publishAt
+		blog.PublishedAt = nil
+	case models.StatusPublished:
+		blog.PublishAt = nil
+		if !blog.IsPublished() || blog.PublishedAt == nil {
+			blog.PublishedAt = &now
+		}
+	case models.StatusDraft, models.StatusArchived:
+		blog.PublishAt = nil
+	}
+
+	blog.Status = status
+	return nil
+}
+
+func (h *Handler) PublishBlog(c *gin.Context) {
+	var publishReq models.PublishRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&publishReq); err != nil {
+			apierror.Abort(c, apierror.FromBinding(err))
+			return
+		}
+	}
+
+	status := models.StatusPublished
+	if publishReq.PublishAt != nil && publishReq.PublishAt.After(time.Now()) {
+		status = models.StatusScheduled
+	}
+	h.changeBlogStatus(c, status, publishReq.PublishAt)
+}
+
+func (h *Handler) UnpublishBlog(c *gin.Context) {
+	h.changeBlogStatus(c, models.StatusDraft, nil)
+}
+
+func (h *Handler) ArchiveBlog(c *gin.Context) {
+	h.changeBlogStatus(c, models.StatusArchived, nil)
+}
+
+func (h *Handler) changeBlogStatus(c *gin.Context, status string, publishAt *time.Time) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	blogId := c.Param("bid")
+	bid, err := primitive.ObjectIDFromHex(blogId)
+	if err != nil {
+		apierror.Abort(c, invalidID("blog"))
+		return
+	}
+
+	actor := policy.ActorFromUserData(c.MustGet("userData").(map[string]string))
+
+	blog, err := h.Blogs.FindByID(ctx, bid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errBlogNotFound)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Updating blog failed, please try again later.")
+		return
+	}
+
+	if !policy.Can(actor, policy.UpdateBlog, blog.Author) {
+		apierror.Abort(c, errNotAllowed)
+		return
+	}
+
+	if err := setPublishState(blog, status, publishAt); err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	err = h.Blogs.UpdateStatus(ctx, blog)
+	if err != nil {
+		serverError(c, err, "Updating blog failed, please try again later.")
+		return
+	}
+	h.indexBlog(ctx, c, blog)
+
+	c.JSON(200, gin.H{"blog": blog})
+}
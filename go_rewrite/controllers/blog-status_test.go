@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"backend/models"
+)
+
+func TestSetPublishStatePublishedAt(t *testing.T) {
+	wentLive := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		blog models.Blog
+		keep bool
+	}{
+		{"already published", models.Blog{Status: models.StatusPublished, PublishedAt: &wentLive}, true},
+		{"republished draft", models.Blog{Status: models.StatusDraft, PublishedAt: &wentLive}, false},
+		{"published when due", models.Blog{Status: models.StatusScheduled}, false},
+		{"published without a time", models.Blog{Status: models.StatusPublished}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			blog := tt.blog
+			if err := setPublishState(&blog, models.StatusPublished, nil); err != nil {
+				t.Fatal(err)
+			}
+			if blog.PublishedAt == nil {
+				t.Fatal("PublishedAt is unset")
+			}
+			if kept := blog.PublishedAt.Equal(wentLive); kept != tt.keep {
+				t.Errorf("PublishedAt = %v, kept = %v, want %v", blog.PublishedAt, kept, tt.keep)
+			}
+			if !tt.keep && blog.PublishedAt.Before(before) {
+				t.Errorf("PublishedAt = %v, want the time of publishing", blog.PublishedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"backend/models"
+	"backend/store"
+)
+
+// The helpers below keep the blogs and comments arrays in step with the
+// documents they reference. Each runs as one transaction, and registers the
+// undo steps used instead where transactions are unavailable.
+
+// createBlog stores blog and adds it to its author's blogs.
+func (h *Handler) createBlog(ctx context.Context, blog *models.Blog) error {
+	return h.Tx.Run(ctx, func(ctx context.Context, undo *store.Undo) error {
+		if err := h.Blogs.Create(ctx, blog); err != nil {
+			return err
+		}
+		undo.Add(func(ctx context.Context) error { return h.Blogs.Delete(ctx, blog.ID) })
+
+		return h.Users.AddBlog(ctx, blog.Author, blog.ID)
+	})
+}
+
+// deleteBlog removes blog together with all of its comments.
+func (h *Handler) deleteBlog(ctx context.Context, blog *models.Blog) error {
+	return h.Tx.Run(ctx, func(ctx context.Context, undo *store.Undo) error {
+		comments, err := h.Comments.FindByIDs(ctx, blog.Comments)
+		if err != nil {
+			return err
+		}
+
+		if err := h.Users.RemoveBlog(ctx, blog.Author, blog.ID); err != nil {
+			return err
+		}
+		undo.Add(func(ctx context.Context) error { return h.Users.AddBlog(ctx, blog.Author, blog.ID) })
+
+		if err := h.Blogs.Delete(ctx, blog.ID); err != nil {
+			return err
+		}
+		undo.Add(func(ctx context.Context) error { return h.Blogs.Create(ctx, blog) })
+
+		if err := h.Comments.DeleteByBlog(ctx, blog.ID); err != nil {
+			return err
+		}
+		undo.Add(func(ctx context.Context) error { return restoreComments(ctx, h.Comments, comments) })
+		return nil
+	})
+}
+
+// createComment stores comment and adds it to its blog's comments.
+func (h *Handler) createComment(ctx context.Context, comment *models.Comment) error {
+	return h.Tx.Run(ctx, func(ctx context.Context, undo *store.Undo) error {
+		if err := h.Comments.Create(ctx, comment); err != nil {
+			return err
+		}
+		undo.Add(func(ctx context.Context) error { return h.Comments.Delete(ctx, comment.ID) })
+
+		return h.Blogs.AddComment(ctx, comment.Blog, comment.ID)
+	})
+}
+
+// deleteComment removes comment from its blog, or blanks it when it still
+// has replies. Deleted placeholders it leaves without replies go as well.
+func (h *Handler) deleteComment(ctx context.Context, comment *models.Comment) error {
+	return h.Tx.Run(ctx, func(ctx context.Context, undo *store.Undo) error {
+		replies, err := h.Comments.CountReplies(ctx, comment.ID)
+		if err != nil {
+			return err
+		}
+		if replies > 0 {
+			return h.Comments.MarkDeleted(ctx, comment.ID)
+		}
+
+		for {
+			if err := h.removeComment(ctx, undo, comment); err != nil {
+				return err
+			}
+			if comment.ParentID.IsZero() {
+				return nil
+			}
+
+			parent, err := h.Comments.FindByID(ctx, comment.ParentID)
+			if errors.Is(err, store.ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if !parent.Deleted {
+				return nil
+			}
+			if replies, err := h.Comments.CountReplies(ctx, parent.ID); err != nil || replies > 0 {
+				return err
+			}
+			comment = parent
+		}
+	})
+}
+
+func (h *Handler) removeComment(ctx context.Context, undo *store.Undo, comment *models.Comment) error {
+	if err := h.Comments.Delete(ctx, comment.ID); err != nil {
+		return err
+	}
+	removed := *comment
+	undo.Add(func(ctx context.Context) error { return h.Comments.Create(ctx, &removed) })
+
+	if err := h.Blogs.RemoveComment(ctx, comment.ID); err != nil {
+		return err
+	}
+	undo.Add(func(ctx context.Context) error { return h.Blogs.AddComment(ctx, removed.Blog, removed.ID) })
+	return nil
+}
+
+func restoreComments(ctx context.Context, comments store.CommentStore, docs []models.Comment) error {
+	var errs []error
+	for i := range docs {
+		if err := comments.Create(ctx, &docs[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
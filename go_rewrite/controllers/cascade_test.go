@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"backend/models"
+	"backend/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errInjected = errors.New("injected failure")
+
+// faults makes the nth call to one store method fail before it writes.
+type faults struct {
+	method string
+	nth    int
+	calls  int
+}
+
+func (f *faults) check(method string) error {
+	if f.method != method {
+		return nil
+	}
+	f.calls++
+	if f.calls == f.nth {
+		return errInjected
+	}
+	return nil
+}
+
+type faultyUserStore struct {
+	store.UserStore
+	f *faults
+}
+
+func (s faultyUserStore) AddBlog(ctx context.Context, uid, bid primitive.ObjectID) error {
+	if err := s.f.check("Users.AddBlog"); err != nil {
+		return err
+	}
+	return s.UserStore.AddBlog(ctx, uid, bid)
+}
+
+func (s faultyUserStore) RemoveBlog(ctx context.Context, uid, bid primitive.ObjectID) error {
+	if err := s.f.check("Users.RemoveBlog"); err != nil {
+		return err
+	}
+	return s.UserStore.RemoveBlog(ctx, uid, bid)
+}
+
+type faultyBlogStore struct {
+	store.BlogStore
+	f *faults
+}
+
+func (s faultyBlogStore) Create(ctx context.Context, blog *models.Blog) error {
+	if err := s.f.check("Blogs.Create"); err != nil {
+		return err
+	}
+	return s.BlogStore.Create(ctx, blog)
+}
+
+func (s faultyBlogStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if err := s.f.check("Blogs.Delete"); err != nil {
+		return err
+	}
+	return s.BlogStore.Delete(ctx, id)
+}
+
+func (s faultyBlogStore) AddComment(ctx context.Context, bid, cid primitive.ObjectID) error {
+	if err := s.f.check("Blogs.AddComment"); err != nil {
+		return err
+	}
+	return s.BlogStore.AddComment(ctx, bid, cid)
+}
+
+func (s faultyBlogStore) RemoveComment(ctx context.Context, cid primitive.ObjectID) error {
+	if err := s.f.check("Blogs.RemoveComment"); err != nil {
+		return err
+	}
+	return s.BlogStore.RemoveComment(ctx, cid)
+}
+
+type faultyCommentStore struct {
+	store.CommentStore
+	f *faults
+}
+
+func (s faultyCommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	if err := s.f.check("Comments.Create"); err != nil {
+		return err
+	}
+	return s.CommentStore.Create(ctx, comment)
+}
+
+func (s faultyCommentStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if err := s.f.check("Comments.Delete"); err != nil {
+		return err
+	}
+	return s.CommentStore.Delete(ctx, id)
+}
+
+func (s faultyCommentStore) DeleteByBlog(ctx context.Context, bid primitive.ObjectID) error {
+	if err := s.f.check("Comments.DeleteByBlog"); err != nil {
+		return err
+	}
+	return s.CommentStore.DeleteByBlog(ctx, bid)
+}
+
+// cascadeFixture is a user with one blog holding a top-level comment, a
+// deleted placeholder and the placeholder's only reply.
+type cascadeFixture struct {
+	h      *Handler
+	f      *faults
+	user   *models.User
+	blog   *models.Blog
+	top    *models.Comment
+	parent *models.Comment
+	reply  *models.Comment
+}
+
+func newCascadeFixture(t *testing.T) *cascadeFixture {
+	t.Helper()
+	ctx := context.Background()
+	stores := store.NewMemoryStores()
+	f := &faults{}
+	h := &Handler{
+		Users:    faultyUserStore{stores.Users, f},
+		Blogs:    faultyBlogStore{stores.Blogs, f},
+		Comments: faultyCommentStore{stores.Comments, f},
+		Tx:       stores.Tx,
+	}
+
+	user := &models.User{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"}
+	if err := h.Users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	blog := &models.Blog{Title: "Notes", Slug: "notes", Author: user.ID, Description: "d", Article: "a"}
+	if err := h.createBlog(ctx, blog); err != nil {
+		t.Fatal(err)
+	}
+
+	fx := &cascadeFixture{h: h, f: f, user: user, blog: blog}
+	fx.top = fx.comment(t, primitive.NilObjectID)
+	fx.parent = fx.comment(t, primitive.NilObjectID)
+	fx.reply = fx.comment(t, fx.parent.ID)
+	if err := h.Comments.MarkDeleted(ctx, fx.parent.ID); err != nil {
+		t.Fatal(err)
+	}
+	fx.parent.Deleted = true
+
+	blog, err := h.Blogs.FindByID(ctx, blog.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fx.blog = blog
+	return fx
+}
+
+func (fx *cascadeFixture) comment(t *testing.T, parent primitive.ObjectID) *models.Comment {
+	t.Helper()
+	comment := &models.Comment{User: fx.user.ID, Content: "c", Date: time.Now(), Blog: fx.blog.ID, ParentID: parent}
+	if !parent.IsZero() {
+		comment.Depth = 1
+	}
+	if err := fx.h.createComment(context.Background(), comment); err != nil {
+		t.Fatal(err)
+	}
+	return comment
+}
+
+// commentIDs returns the fixture's comments plus extra, so that the check
+// also covers comments created during the test.
+func (fx *cascadeFixture) commentIDs(extra ...*models.Comment) []primitive.ObjectID {
+	ids := []primitive.ObjectID{fx.top.ID, fx.parent.ID, fx.reply.ID}
+	for _, c := range extra {
+		if !c.ID.IsZero() {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// checkConsistent fails t if the user's blogs, the blogs' comments and the
+// comments' blogs and parents do not reference each other correctly.
+func (fx *cascadeFixture) checkConsistent(t *testing.T, commentIDs []primitive.ObjectID) {
+	t.Helper()
+	ctx := context.Background()
+
+	user, err := fx.h.Users.FindByID(ctx, fx.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bid := range user.Blogs {
+		if _, err := fx.h.Blogs.FindByID(ctx, bid); err != nil {
+			t.Errorf("user lists blog %s: %v", bid.Hex(), err)
+		}
+	}
+
+	blog, err := fx.h.Blogs.FindByID(ctx, fx.blog.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		blog = nil
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if listed := slices.Contains(user.Blogs, fx.blog.ID); listed != (blog != nil) {
+		t.Errorf("blog exists = %v, but the user lists it = %v", blog != nil, listed)
+	}
+
+	comments, err := fx.h.Comments.FindByIDs(ctx, commentIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := make(map[primitive.ObjectID]bool, len(comments))
+	for _, c := range comments {
+		stored[c.ID] = true
+	}
+	if blog != nil {
+		for _, cid := range blog.Comments {
+			if !stored[cid] {
+				t.Errorf("blog lists missing comment %s", cid.Hex())
+			}
+		}
+	}
+	for _, c := range comments {
+		if blog == nil || c.Blog != blog.ID {
+			t.Errorf("comment %s is orphaned", c.ID.Hex())
+			continue
+		}
+		if !slices.Contains(blog.Comments, c.ID) {
+			t.Errorf("blog does not list comment %s", c.ID.Hex())
+		}
+		if !c.ParentID.IsZero() && !stored[c.ParentID] {
+			t.Errorf("reply %s has no parent %s", c.ID.Hex(), c.ParentID.Hex())
+		}
+	}
+}
+
+// exists reports which of ids are stored.
+func (fx *cascadeFixture) exists(t *testing.T, ids ...primitive.ObjectID) []bool {
+	t.Helper()
+	found := make([]bool, len(ids))
+	for i, id := range ids {
+		_, err := fx.h.Comments.FindByID(context.Background(), id)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			t.Fatal(err)
+		}
+		found[i] = err == nil
+	}
+	return found
+}
+
+func TestCreateBlogCompensates(t *testing.T) {
+	for _, method := range []string{"Blogs.Create", "Users.AddBlog"} {
+		t.Run(method, func(t *testing.T) {
+			fx := newCascadeFixture(t)
+			fx.f.method, fx.f.nth = method, 1
+
+			blog := &models.Blog{Title: "Draft", Slug: "draft", Author: fx.user.ID, Description: "d", Article: "a"}
+			if err := fx.h.createBlog(context.Background(), blog); !errors.Is(err, errInjected) {
+				t.Fatalf("createBlog = %v, want the injected failure", err)
+			}
+
+			if !blog.ID.IsZero() {
+				if _, err := fx.h.Blogs.FindByID(context.Background(), blog.ID); !errors.Is(err, store.ErrNotFound) {
+					t.Errorf("blog left behind: %v", err)
+				}
+			}
+			user, err := fx.h.Users.FindByID(context.Background(), fx.user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(user.Blogs, []primitive.ObjectID{fx.blog.ID}) {
+				t.Errorf("user blogs = %v, want only %s", user.Blogs, fx.blog.ID.Hex())
+			}
+			fx.checkConsistent(t, fx.commentIDs())
+		})
+	}
+}
+
+func TestDeleteBlogCompensates(t *testing.T) {
+	for _, method := range []string{"Users.RemoveBlog", "Blogs.Delete", "Comments.DeleteByBlog"} {
+		t.Run(method, func(t *testing.T) {
+			fx := newCascadeFixture(t)
+			fx.f.method, fx.f.nth = method, 1
+
+			if err := fx.h.deleteBlog(context.Background(), fx.blog); !errors.Is(err, errInjected) {
+				t.Fatalf("deleteBlog = %v, want the injected failure", err)
+			}
+
+			blog, err := fx.h.Blogs.FindByID(context.Background(), fx.blog.ID)
+			if err != nil {
+				t.Fatalf("blog not restored: %v", err)
+			}
+			if len(blog.Comments) != 3 {
+				t.Errorf("blog has %d comments, want 3", len(blog.Comments))
+			}
+			if got := fx.exists(t, fx.commentIDs()...); slices.Contains(got, false) {
+				t.Errorf("comments exist = %v, want all restored", got)
+			}
+			fx.checkConsistent(t, fx.commentIDs())
+		})
+	}
+}
+
+func TestDeleteBlogRemovesComments(t *testing.T) {
+	fx := newCascadeFixture(t)
+
+	if err := fx.h.deleteBlog(context.Background(), fx.blog); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fx.exists(t, fx.commentIDs()...); slices.Contains(got, true) {
+		t.Errorf("comments exist = %v, want all deleted", got)
+	}
+	fx.checkConsistent(t, fx.commentIDs())
+}
+
+func TestCreateCommentCompensates(t *testing.T) {
+	for _, method := range []string{"Comments.Create", "Blogs.AddComment"} {
+		t.Run(method, func(t *testing.T) {
+			fx := newCascadeFixture(t)
+			fx.f.method, fx.f.nth = method, 1
+
+			comment := &models.Comment{User: fx.user.ID, Content: "c", Date: time.Now(), Blog: fx.blog.ID, ParentID: fx.top.ID, Depth: 1}
+			if err := fx.h.createComment(context.Background(), comment); !errors.Is(err, errInjected) {
+				t.Fatalf("createComment = %v, want the injected failure", err)
+			}
+
+			if !comment.ID.IsZero() {
+				if got := fx.exists(t, comment.ID); got[0] {
+					t.Error("comment left behind")
+				}
+			}
+			fx.checkConsistent(t, fx.commentIDs(comment))
+		})
+	}
+}
+
+func TestDeleteCommentCompensates(t *testing.T) {
+	// Deleting the reply also removes its deleted parent, so the second call
+	// of each method fails halfway through the cascade.
+	for _, tc := range []struct {
+		method string
+		nth    int
+	}{
+		{"Comments.Delete", 1},
+		{"Blogs.RemoveComment", 1},
+		{"Comments.Delete", 2},
+		{"Blogs.RemoveComment", 2},
+	} {
+		t.Run(fmt.Sprintf("%s/%d", tc.method, tc.nth), func(t *testing.T) {
+			fx := newCascadeFixture(t)
+			fx.f.method, fx.f.nth = tc.method, tc.nth
+
+			if err := fx.h.deleteComment(context.Background(), fx.reply); !errors.Is(err, errInjected) {
+				t.Fatalf("deleteComment = %v, want the injected failure", err)
+			}
+
+			if got := fx.exists(t, fx.commentIDs()...); slices.Contains(got, false) {
+				t.Errorf("comments exist = %v, want all restored", got)
+			}
+			fx.checkConsistent(t, fx.commentIDs())
+		})
+	}
+}
+
+func TestDeleteCommentRemovesDeletedParent(t *testing.T) {
+	fx := newCascadeFixture(t)
+
+	if err := fx.h.deleteComment(context.Background(), fx.reply); err != nil {
+		t.Fatal(err)
+	}
+
+	got := fx.exists(t, fx.top.ID, fx.parent.ID, fx.reply.ID)
+	if want := []bool{true, false, false}; !slices.Equal(got, want) {
+		t.Errorf("comments exist = %v, want %v", got, want)
+	}
+	fx.checkConsistent(t, fx.commentIDs())
+}
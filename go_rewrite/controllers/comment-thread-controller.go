@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"backend/apierror"
+	"backend/models"
+	"backend/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	threadPageSize = 20
+	replyPageSize  = 10
+)
+
+const deletedCommentContent = "[deleted]"
+
+// commentTree indexes comments by parent, each level ordered oldest first.
+type commentTree struct {
+	children map[primitive.ObjectID][]models.Comment
+	users    map[primitive.ObjectID]models.User
+}
+
+// loadComments renders a page of the replies to parent on blog, the zero
+// parent for its top-level comments, and returns how many replies there are
+// in all. Only that page and its descendants are loaded.
+func (h *Handler) loadComments(ctx context.Context, blog *models.Blog, parent primitive.ObjectID, offset, limit int) ([]models.CommentResponse, int, error) {
+	if len(blog.Comments) == 0 {
+		return []models.CommentResponse{}, 0, nil
+	}
+
+	page, total, err := h.Comments.ListReplies(ctx, blog.ID, parent, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tree := &commentTree{children: make(map[primitive.ObjectID][]models.Comment)}
+	userIDs := make([]primitive.ObjectID, 0, len(page))
+	level := make([]primitive.ObjectID, 0, len(page))
+	for _, comment := range page {
+		userIDs = append(userIDs, comment.User)
+		level = append(level, comment.ID)
+	}
+	// Each round loads the replies one level further down.
+	for len(level) > 0 {
+		replies, err := h.Comments.FindReplies(ctx, level)
+		if err != nil {
+			return nil, 0, err
+		}
+		level = level[:0]
+		for _, reply := range replies {
+			tree.children[reply.ParentID] = append(tree.children[reply.ParentID], reply)
+			userIDs = append(userIDs, reply.User)
+			level = append(level, reply.ID)
+		}
+	}
+	for _, siblings := range tree.children {
+		slices.SortFunc(siblings, func(a, b models.Comment) int {
+			if c := a.Date.Compare(b.Date); c != 0 {
+				return c
+			}
+			return bytes.Compare(a.ID[:], b.ID[:])
+		})
+	}
+
+	tree.users, err = h.authorMap(ctx, userIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]models.CommentResponse, 0, len(page))
+	for _, comment := range page {
+		responses = append(responses, tree.render(comment))
+	}
+	return responses, int(total), nil
+}
+
+// page renders the replies to parent from offset on, each with up to
+// replyPageSize of its own replies nested below it.
+func (t *commentTree) page(parent primitive.ObjectID, offset, limit int) ([]models.CommentResponse, int) {
+	siblings := t.children[parent]
+	total := len(siblings)
+	start := min(offset, total)
+	end := min(start+limit, total)
+
+	responses := make([]models.CommentResponse, 0, end-start)
+	for _, comment := range siblings[start:end] {
+		responses = append(responses, t.render(comment))
+	}
+	return responses, total
+}
+
+func (t *commentTree) render(comment models.Comment) models.CommentResponse {
+	response := models.CommentResponse{
+		ID:      comment.ID.Hex(),
+		Content: comment.Content,
+		Date:    comment.Date,
+		Blog:    comment.Blog.Hex(),
+		Depth:   comment.Depth,
+		Deleted: comment.Deleted,
+	}
+	if !comment.ParentID.IsZero() {
+		response.ParentID = comment.ParentID.Hex()
+	}
+	if comment.Deleted {
+		response.Content = deletedCommentContent
+	} else {
+		user := t.users[comment.User]
+		response.User = models.Author{FirstName: user.FirstName, LastName: user.LastName}
+	}
+
+	response.Replies, response.ReplyCount = t.page(comment.ID, 0, replyPageSize)
+	return response
+}
+
+// flatten lists comments and their nested replies depth-first, dropping the
+// nesting; each comment's Depth tells clients how far to indent it.
+func flatten(comments []models.CommentResponse) []models.CommentResponse {
+	flat := make([]models.CommentResponse, 0, len(comments))
+	for _, comment := range comments {
+		replies := comment.Replies
+		comment.Replies = nil
+		flat = append(flat, comment)
+		flat = append(flat, flatten(replies)...)
+	}
+	return flat
+}
+
+var errInvalidPage = apierror.New(http.StatusBadRequest, apierror.CodeInvalidQuery, "Invalid paging parameters, please check your query.")
+
+// parsePage reads an offset-based page from the limitKey and offsetKey query
+// parameters, clamping the limit to maxPageSize.
+func parsePage(c *gin.Context, limitKey, offsetKey string, defaultLimit int) (limit, offset int, err error) {
+	limit = defaultLimit
+	if raw := c.Query(limitKey); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return 0, 0, errInvalidPage
+		}
+		limit = min(limit, maxPageSize)
+	}
+	if raw := c.Query(offsetKey); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return 0, 0, errInvalidPage
+		}
+	}
+	return limit, offset, nil
+}
+
+// GetCommentReplies pages through the direct replies to a comment, for
+// threads too long to be returned with the blog.
+func (h *Handler) GetCommentReplies(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	cid, err := primitive.ObjectIDFromHex(c.Param("cid"))
+	if err != nil {
+		apierror.Abort(c, invalidID("comment"))
+		return
+	}
+
+	limit, offset, err := parsePage(c, "limit", "offset", replyPageSize)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	comment, err := h.Comments.FindByID(ctx, cid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errCommentNotFound)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Error Retrieving comments, please try again later.")
+		return
+	}
+
+	blog, err := h.Blogs.FindByID(ctx, comment.Blog)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !canView(actorFromContext(c), blog)) {
+		apierror.Abort(c, errCommentNotFound)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Error Retrieving comments, please try again later.")
+		return
+	}
+
+	replies, total, err := h.loadComments(ctx, blog, cid, offset, limit)
+	if err != nil {
+		serverError(c, err, "Error Retrieving comments, please try again later.")
+		return
+	}
+
+	if c.Query("format") == "flat" {
+		replies = flatten(replies)
+	}
+	c.JSON(200, models.CommentThreadResponse{Comments: replies, Total: total})
+}
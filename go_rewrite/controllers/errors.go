@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes specific to these handlers; apierror holds the shared ones.
+// Like those, they are part of the API and must not change.
+const (
+	codeBlogNotFound          = "blog_not_found"
+	codeCommentNotFound       = "comment_not_found"
+	codeParentNotFound        = "parent_comment_not_found"
+	codeUserNotFound          = "user_not_found"
+	codeTagNotFound           = "tag_not_found"
+	codeUserExists            = "user_exists"
+	codeInvalidCredentials    = "invalid_credentials"
+	codeInvalidRefreshToken   = "invalid_refresh_token"
+	codeRefreshTokenExpired   = "refresh_token_expired"
+	codeInvalidOneTimeToken   = "invalid_token"
+	codeEmailAlreadyVerified  = "email_already_verified"
+	codeVerificationThrottled = "verification_recently_sent"
+	codeInvalidPublishAt      = "invalid_publish_at"
+	codeCommentDeleted        = "comment_deleted"
+	codeReplyTooDeep          = "reply_too_deep"
+	codeMissingSearchQuery    = "missing_search_query"
+	codeAccountLocked         = "account_locked"
+	codeTwoFactorEnabled      = "two_factor_already_enabled"
+	codeTwoFactorNotEnabled   = "two_factor_not_enabled"
+	codeTwoFactorNotSetUp     = "two_factor_not_set_up"
+	codeInvalidTwoFactorCode  = "invalid_two_factor_code"
+	codeInvalidChallenge      = "invalid_challenge_token"
+	codeAPITokenNotFound      = "api_token_not_found"
+	codeTooManyAPITokens      = "too_many_api_tokens"
+	codeInvalidExpiresAt      = "invalid_expires_at"
+)
+
+var (
+	errBlogNotFound    = apierror.New(http.StatusNotFound, codeBlogNotFound, "Could not find blog.")
+	errCommentNotFound = apierror.New(http.StatusNotFound, codeCommentNotFound, "Could not find comment.")
+	errParentNotFound  = apierror.New(http.StatusNotFound, codeParentNotFound, "Could not find parent comment.")
+	errUserNotFound    = apierror.New(http.StatusNotFound, codeUserNotFound, "Could not find user.")
+	errTagNotFound     = apierror.New(http.StatusNotFound, codeTagNotFound, "Could not find tag.")
+
+	// errNotAllowed is for an authenticated actor acting on someone else's
+	// blog or comment.
+	errNotAllowed = apierror.New(http.StatusForbidden, apierror.CodeForbidden, "You are not allowed to do this.")
+
+	errUserExists          = apierror.New(http.StatusConflict, codeUserExists, "User exists already, please login instead.")
+	errInvalidCredentials  = apierror.New(http.StatusUnauthorized, codeInvalidCredentials, "Invalid credentials, could not log you in.")
+	errInvalidRefreshToken = apierror.New(http.StatusUnauthorized, codeInvalidRefreshToken, "Invalid refresh token, please login again.")
+	errRefreshTokenExpired = apierror.New(http.StatusUnauthorized, codeRefreshTokenExpired, "Refresh token expired, please login again.")
+
+	errAccountLocked = apierror.New(http.StatusTooManyRequests, codeAccountLocked, "Too many failed logins, please try again later.")
+	errTooManyLogins = apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "Too many login attempts, please try again later.")
+
+	errTwoFactorEnabled     = apierror.New(http.StatusConflict, codeTwoFactorEnabled, "Two-factor authentication is already enabled.")
+	errTwoFactorNotEnabled  = apierror.New(http.StatusConflict, codeTwoFactorNotEnabled, "Two-factor authentication is not enabled.")
+	errTwoFactorNotSetUp    = apierror.New(http.StatusConflict, codeTwoFactorNotSetUp, "Set up two-factor authentication first.")
+	errInvalidTwoFactorCode = apierror.New(http.StatusUnauthorized, codeInvalidTwoFactorCode, "Invalid two-factor code.")
+	// errTwoFactorCodeMismatch is for the code confirming a new authenticator,
+	// where the user is already signed in.
+	errTwoFactorCodeMismatch = apierror.New(http.StatusUnprocessableEntity, codeInvalidTwoFactorCode, "The code does not match, please check the authenticator's clock and try again.")
+	errInvalidChallenge      = apierror.New(http.StatusUnauthorized, codeInvalidChallenge, "Login expired, please enter your password again.")
+
+	errAPITokenNotFound = apierror.New(http.StatusNotFound, codeAPITokenNotFound, "Could not find API token.")
+	errTooManyAPITokens = apierror.New(http.StatusConflict, codeTooManyAPITokens, fmt.Sprintf("You can have at most %d API tokens, please revoke one first.", maxAPITokens))
+	errInvalidExpiresAt = apierror.New(http.StatusUnprocessableEntity, codeInvalidExpiresAt, "expiresAt must be in the future.")
+)
+
+// invalidID is the error for a malformed ObjectID in the path or body; what
+// names the kind of document, as in "blog".
+func invalidID(what string) *apierror.Error {
+	return apierror.New(http.StatusBadRequest, apierror.CodeInvalidID, fmt.Sprintf("Invalid %s ID", what))
+}
+
+// serverError reports an unexpected err. The client only sees message, and
+// err is logged with the request's ID.
+func serverError(c *gin.Context, err error, message string) {
+	apierror.Abort(c, apierror.Internal(err, message))
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/config"
+	"backend/keyring"
+	"backend/mailer"
+	"backend/policy"
+	"backend/ratelimit"
+	"backend/search"
+	"backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler carries the dependencies shared by every route handler.
+type Handler struct {
+	Config *config.Config
+
+	Users    store.UserStore
+	Blogs    store.BlogStore
+	Comments store.CommentStore
+
+	RefreshTokens store.RefreshTokenStore
+	Revocations   store.RevocationStore
+	OneTimeTokens store.OneTimeTokenStore
+	APITokens     store.APITokenStore
+
+	Tx store.Transactor
+	DB store.Pinger
+
+	Keys *keyring.Keyring
+
+	Mailer  mailer.Mailer
+	Search  search.Searcher
+	Limiter *ratelimit.Limiter
+
+	draining   atomic.Bool
+	background sync.WaitGroup
+}
+
+func NewHandler(cfg *config.Config, stores *store.Stores, keys *keyring.Keyring, mail mailer.Mailer, searcher search.Searcher, limits ratelimit.Store) *Handler {
+	return &Handler{
+		Config: cfg,
+
+		Users:    stores.Users,
+		Blogs:    stores.Blogs,
+		Comments: stores.Comments,
+
+		RefreshTokens: stores.RefreshTokens,
+		Revocations:   stores.Revocations,
+		OneTimeTokens: stores.OneTimeTokens,
+		APITokens:     stores.APITokens,
+
+		Tx: stores.Tx,
+		DB: stores.DB,
+
+		Keys: keys,
+
+		Mailer:  mail,
+		Search:  searcher,
+		Limiter: ratelimit.New(limits),
+	}
+}
+
+// requestContext derives the context for a request's database work from the
+// request, so that it is cancelled when the client goes away, and bounds it by
+// the route's deadline.
+func (h *Handler) requestContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.Request.Context(), h.Config.RouteTimeout(c.Request.Method, c.FullPath()))
+}
+
+// detachedTimeout bounds work that goes on after the response was sent.
+const detachedTimeout = time.Minute
+
+// runDetached calls fn once the handler has returned, with a context that is
+// not cancelled with the request, so that neither how long fn takes nor
+// whether it fails shows in the response. fn must not use c.
+func (h *Handler) runDetached(c *gin.Context, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), detachedTimeout)
+	h.background.Add(1)
+	go func() {
+		defer h.background.Done()
+		defer cancel()
+		fn(ctx)
+	}()
+}
+
+// Wait blocks until the work handlers left running after their responses
+// has finished.
+func (h *Handler) Wait() {
+	h.background.Wait()
+}
+
+// actorFromContext returns the authenticated actor, or policy.Anonymous on
+// routes behind OptionalAuth when no valid token was sent.
+func actorFromContext(c *gin.Context) policy.Actor {
+	userData, ok := c.Get("userData")
+	if !ok {
+		return policy.Anonymous
+	}
+	return policy.ActorFromUserData(userData.(map[string]string))
+}
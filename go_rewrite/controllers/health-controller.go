@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout is kept short so a slow database fails the probe instead
+// of stalling it past the orchestrator's own timeout.
+const readinessTimeout = 2 * time.Second
+
+// Healthz is the liveness probe: answering at all means the process is up.
+func (h *Handler) Healthz(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "ok"})
+}
+
+// Drain makes Readyz fail from now on, so that load balancers stop routing
+// new traffic to this instance while it shuts down.
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
+
+// Readyz is the readiness probe. It fails while the database is unreachable
+// and once the instance is draining, so no traffic is routed to an instance
+// that cannot serve it.
+func (h *Handler) Readyz(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining", "message": "Shutting down."})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	if err := h.DB.Ping(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "message": "Database is unreachable."})
+		return
+	}
+
+	c.JSON(200, gin.H{"status": "ok"})
+}
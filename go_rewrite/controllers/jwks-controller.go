@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"fmt"
+
+	"backend/keyring"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksMaxAge is how long verifiers may cache the key set. It must stay below
+// keyring.PublishAhead, or they could miss a key that has started signing.
+const jwksMaxAge = keyring.PublishAhead / 4
+
+// JWKS serves the public keys access tokens are verified with.
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+	c.JSON(200, h.Keys.JWKS())
+}
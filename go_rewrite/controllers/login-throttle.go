@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"backend/apierror"
+	"backend/logging"
+	"backend/metrics"
+	"backend/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logins are limited per account on top of the per-IP limit on the route,
+// which an attacker spreading guesses over many addresses would evade. Like
+// the route limits, they let logins through when the limiter's store fails.
+
+// loginAccount keys the limits by the trimmed, lowercased email. FindByEmail
+// matches exactly, so this is deliberately coarser: guesses at any spelling
+// of an address count against the same account rather than each getting
+// their own allowance.
+func loginAccount(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// allowLogin aborts with 429 and reports false when account is locked out or
+// has used up its login attempts.
+func (h *Handler) allowLogin(c *gin.Context, ctx context.Context, account string) bool {
+	lockedFor, err := h.Limiter.LockedFor(ctx, account, h.Config.LoginLockout())
+	if err != nil {
+		logging.FromContext(c).Error("checking login lockout failed", "error", err)
+	}
+	if lockedFor > 0 {
+		metrics.RateLimited.WithLabelValues("login_lockout").Inc()
+		c.Header("Retry-After", ratelimit.RetryAfter(lockedFor))
+		apierror.Abort(c, errAccountLocked)
+		return false
+	}
+
+	result, err := h.Limiter.Allow(ctx, "login_account:"+account, h.Config.LoginAccountLimit)
+	if err != nil {
+		logging.FromContext(c).Error("checking login rate limit failed", "error", err)
+	}
+	if !result.Allowed {
+		metrics.RateLimited.WithLabelValues("login_account").Inc()
+		c.Header("Retry-After", ratelimit.RetryAfter(result.RetryAfter))
+		apierror.Abort(c, errTooManyLogins)
+		return false
+	}
+	return true
+}
+
+// loginFailed counts a failed login towards account's lockout.
+func (h *Handler) loginFailed(c *gin.Context, ctx context.Context, account string) {
+	lockedFor, err := h.Limiter.Fail(ctx, account, h.Config.LoginLockout())
+	if err != nil {
+		logging.FromContext(c).Error("recording failed login failed", "error", err)
+		return
+	}
+	if lockedFor > 0 {
+		logging.FromContext(c).Warn("account locked after failed logins", "account", account, "locked_for", lockedFor)
+	}
+}
+
+// loginSucceeded clears account's failed logins.
+func (h *Handler) loginSucceeded(c *gin.Context, ctx context.Context, account string) {
+	if err := h.Limiter.Succeed(ctx, account); err != nil {
+		logging.FromContext(c).Error("clearing failed logins failed", "error", err)
+	}
+}
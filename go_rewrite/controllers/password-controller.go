@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"backend/apierror"
+	"backend/logging"
+	"backend/mailer"
+	"backend/models"
+	"backend/store"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var forgotReq models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&forgotReq); err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return
+	}
+
+	// The lookup, the token and the email all happen after the response, so
+	// that neither its status nor its timing reveal whether the email belongs
+	// to an account.
+	logger := logging.FromContext(c)
+	h.runDetached(c, func(ctx context.Context) {
+		if err := h.sendPasswordReset(ctx, forgotReq.Email); err != nil {
+			logger.Error("handling password reset request failed", "error", err)
+		}
+	})
+
+	c.JSON(200, gin.H{"message": "If an account exists for this email, a reset link has been sent."})
+}
+
+// sendPasswordReset mails a reset link to the account with email, if there
+// is one.
+func (h *Handler) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := h.Users.FindByEmail(ctx, email)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := h.issueOneTimeToken(ctx, user.ID, models.PurposePasswordReset, h.Config.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := h.Config.FrontendURL + "/reset-password?token=" + url.QueryEscape(token)
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.FirstName, h.Config.PasswordResetTTL, link),
+	})
+}
+
+func (h *Handler) ResetPassword(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	var resetReq models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&resetReq); err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return
+	}
+
+	token, err := h.OneTimeTokens.Consume(ctx, models.PurposePasswordReset, hashToken(resetReq.Token))
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, apierror.New(http.StatusUnprocessableEntity, codeInvalidOneTimeToken, "Invalid or expired reset token."))
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Resetting password failed, please try again later.")
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(resetReq.Password), h.Config.BcryptCost)
+	if err != nil {
+		serverError(c, err, "Resetting password failed, please try again later.")
+		return
+	}
+
+	err = h.Users.UpdatePassword(ctx, token.User, string(hashedPassword))
+	if err != nil {
+		serverError(c, err, "Resetting password failed, please try again later.")
+		return
+	}
+
+	err = h.revokeSessions(ctx, token.User)
+	if err != nil {
+		serverError(c, err, "Resetting password failed, please try again later.")
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Password reset, please login with your new password."})
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"backend/apierror"
+	"backend/logging"
+	"backend/models"
+	"backend/search"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// indexBlog and indexComment keep the search index current. A failure only
+// leaves search results stale, so it is logged rather than returned.
+func (h *Handler) indexBlog(ctx context.Context, c *gin.Context, blog *models.Blog) {
+	if err := h.Search.IndexBlog(ctx, blog); err != nil {
+		logging.FromContext(c).Error("indexing blog for search failed", "blog_id", blog.ID.Hex(), "error", err)
+	}
+}
+
+func (h *Handler) indexComment(ctx context.Context, c *gin.Context, comment *models.Comment) {
+	if err := h.Search.IndexComment(ctx, comment); err != nil {
+		logging.FromContext(c).Error("indexing comment for search failed", "comment_id", comment.ID.Hex(), "error", err)
+	}
+}
+
+func (h *Handler) SearchBlogs(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		apierror.Abort(c, apierror.New(http.StatusBadRequest, codeMissingSearchQuery, "Please provide a search query."))
+		return
+	}
+
+	limit, offset, err := parsePage(c, "limit", "offset", defaultPageSize)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	query := search.Query{
+		Text:     text,
+		Comments: c.Query("comments") == "true",
+		Offset:   offset,
+		Limit:    limit,
+	}
+
+	result, err := h.Search.Search(ctx, query)
+	if err != nil {
+		serverError(c, err, "Searching failed, please try again later.")
+		return
+	}
+
+	authorIDs := make([]primitive.ObjectID, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		authorIDs = append(authorIDs, hit.Blog.Author)
+	}
+	authors, err := h.authorMap(ctx, authorIDs)
+	if err != nil {
+		serverError(c, err, "Searching failed, please try again later.")
+		return
+	}
+
+	results := make([]models.SearchResult, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		author := authors[hit.Blog.Author]
+		results = append(results, models.SearchResult{
+			ID:          hit.Blog.ID.Hex(),
+			Title:       hit.Blog.Title,
+			Slug:        hit.Blog.Slug,
+			Author:      models.Author{FirstName: author.FirstName, LastName: author.LastName},
+			Description: hit.Blog.Description,
+			Tags:        hit.Blog.Tags,
+			PublishedAt: hit.Blog.PublishedAt,
+			Score:       hit.Score,
+			Snippets:    hit.Snippets,
+		})
+	}
+
+	c.JSON(200, models.SearchResponse{Query: text, Results: results, Total: result.Total})
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"backend/apierror"
+	"backend/models"
+	"backend/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sendAuthResponse writes response, moving its tokens into session cookies
+// when they are enabled, so that scripts never see them.
+func (h *Handler) sendAuthResponse(c *gin.Context, status int, response models.UserResponse) {
+	if h.Config.SessionCookies {
+		csrf, err := session.Set(c, h.Config, response.Token, response.RefreshToken)
+		if err != nil {
+			serverError(c, err, "Signing in failed, please try again later.")
+			return
+		}
+		response.Token, response.RefreshToken, response.CSRFToken = "", "", csrf
+	}
+
+	c.JSON(status, response)
+}
+
+// bindRefreshToken reads the refresh token from the session cookie, or else
+// from the body. It reports false after aborting on a bad body.
+func (h *Handler) bindRefreshToken(c *gin.Context) (string, bool) {
+	if token := session.RefreshToken(c, h.Config); token != "" {
+		return token, true
+	}
+
+	var refreshReq models.RefreshRequest
+	if err := c.ShouldBindJSON(&refreshReq); err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return "", false
+	}
+	return refreshReq.RefreshToken, true
+}
+
+// CSRFToken returns the session's CSRF token, for frontends on another origin
+// that cannot read the cookie, such as after a page reload.
+func (h *Handler) CSRFToken(c *gin.Context) {
+	csrf, err := session.CSRFToken(c, h.Config)
+	if err != nil {
+		serverError(c, err, "Could not issue a CSRF token, please try again later.")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, gin.H{"csrfToken": csrf})
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"unicode/utf8"
+
+	"backend/apierror"
+	"backend/slug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// normalizeTags normalizes tags with slug.Tag and drops duplicates. Tags
+// that normalize to nothing or grow too long are rejected.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for i, tag := range tags {
+		t := slug.Tag(tag)
+		if t == "" {
+			return nil, invalidTag(i, "required", "must not be blank")
+		}
+		if utf8.RuneCountInString(t) > slug.MaxTagLength {
+			return nil, invalidTag(i, "max", fmt.Sprintf("must have at most %d characters", slug.MaxTagLength))
+		}
+		if !slices.Contains(normalized, t) {
+			normalized = append(normalized, t)
+		}
+	}
+	return normalized, nil
+}
+
+// invalidTag reports tags[i] the way a failed binding would.
+func invalidTag(i int, code, message string) *apierror.Error {
+	return &apierror.Error{
+		Status: http.StatusUnprocessableEntity,
+		Code:   apierror.CodeValidationFailed,
+		Detail: "Invalid inputs passed, please check your data.",
+		Fields: []apierror.FieldError{{Field: fmt.Sprintf("tags[%d]", i), Code: code, Message: message}},
+	}
+}
+
+func (h *Handler) GetTags(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	counts, err := h.Blogs.TagCounts(ctx)
+	if err != nil {
+		serverError(c, err, "Error Retrieving tags, please try again later.")
+		return
+	}
+
+	c.JSON(200, gin.H{"tags": counts})
+}
+
+func (h *Handler) GetTagBlogs(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	tag := slug.Tag(c.Param("tag"))
+	if tag == "" {
+		apierror.Abort(c, errTagNotFound)
+		return
+	}
+
+	query, withTotal, err := parseBlogQuery(c)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	query.VisibleTo = actorFromContext(c).ID
+	query.Tag = tag
+
+	response, err := h.listBlogs(ctx, query, withTotal)
+	if err != nil {
+		serverError(c, err, "Error Retrieving data, please try again later.")
+		return
+	}
+
+	if len(response.Blogs) == 0 && query.Cursor == nil {
+		apierror.Abort(c, errTagNotFound)
+		return
+	}
+
+	response.Tag = tag
+	c.JSON(200, response)
+}
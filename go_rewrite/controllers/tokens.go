@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"backend/keyring"
+	"backend/logging"
+	"backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// randomToken returns 32 random bytes encoded for use in URLs and JSON bodies.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is how opaque tokens are persisted; the plaintext is only ever sent to the client.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *Handler) signAccessToken(user *models.User) (string, error) {
+	jti, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return h.Keys.Sign(jwt.MapClaims{
+		"iss":       h.Config.TokenIssuer,
+		"aud":       h.Config.TokenAudience,
+		"sub":       user.ID.Hex(),
+		"userId":    user.ID.Hex(),
+		"email":     user.Email,
+		"firstName": user.FirstName,
+		"lastName":  user.LastName,
+		"role":      models.EffectiveRole(user.Role),
+		"jti":       jti,
+		"iat":       now.Unix(),
+		"nbf":       now.Unix(),
+		"exp":       now.Add(h.Config.AccessTokenTTL).Unix(),
+	})
+}
+
+// challengeAudience keeps 2FA challenge tokens and access tokens from being
+// accepted in place of each other.
+func (h *Handler) challengeAudience() string {
+	return h.Config.TokenAudience + "/2fa"
+}
+
+// signChallengeToken vouches that user entered the right password, so that
+// LoginTwoFactor only needs the second factor.
+func (h *Handler) signChallengeToken(user *models.User) (string, error) {
+	jti, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return h.Keys.Sign(jwt.MapClaims{
+		"iss": h.Config.TokenIssuer,
+		"aud": h.challengeAudience(),
+		"sub": user.ID.Hex(),
+		"jti": jti,
+		"iat": now.Unix(),
+		"exp": now.Add(h.Config.TwoFactorChallengeTTL).Unix(),
+	})
+}
+
+// parseChallengeToken returns the user a valid challenge token was issued to.
+func (h *Handler) parseChallengeToken(tokenString string) (primitive.ObjectID, error) {
+	token, err := jwt.Parse(tokenString, h.Keys.Keyfunc,
+		jwt.WithValidMethods(keyring.Algorithms),
+		jwt.WithIssuer(h.Config.TokenIssuer),
+		jwt.WithAudience(h.challengeAudience()),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	sub, err := token.Claims.GetSubject()
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return primitive.ObjectIDFromHex(sub)
+}
+
+// issueRefreshToken stores a new refresh token in family and returns its plaintext.
+func (h *Handler) issueRefreshToken(ctx context.Context, uid, family primitive.ObjectID) (string, error) {
+	plain, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = h.RefreshTokens.Create(ctx, &models.RefreshToken{
+		User:      uid,
+		Family:    family,
+		TokenHash: hashToken(plain),
+		ExpiresAt: time.Now().Add(h.Config.RefreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// revokeRefreshFamily ends the session token belongs to after it was reused.
+// The client is refused either way, so a failure is only logged, loudly, as
+// the stolen chain may still be refreshable.
+func (h *Handler) revokeRefreshFamily(c *gin.Context, ctx context.Context, token *models.RefreshToken) {
+	if err := h.RefreshTokens.RevokeFamily(ctx, token.Family); err != nil {
+		logging.FromContext(c).Error("revoking reused refresh token family failed",
+			"user_id", token.User.Hex(), "family", token.Family.Hex(), "error", err)
+	}
+}
+
+// authResponse signs a fresh access token and rotates the refresh token within family.
+func (h *Handler) authResponse(ctx context.Context, user *models.User, family primitive.ObjectID) (models.UserResponse, error) {
+	tokenString, err := h.signAccessToken(user)
+	if err != nil {
+		return models.UserResponse{}, err
+	}
+
+	refreshToken, err := h.issueRefreshToken(ctx, user.ID, family)
+	if err != nil {
+		return models.UserResponse{}, err
+	}
+
+	return models.UserResponse{
+		ID:           user.ID.Hex(),
+		Email:        user.Email,
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+
+		EmailVerified:    user.EmailVerified,
+		Role:             models.EffectiveRole(user.Role),
+		TwoFactorEnabled: user.TwoFactor.Enabled,
+	}, nil
+}
+
+// issueOneTimeToken replaces any outstanding token of uid for purpose and
+// returns the plaintext of the new one.
+func (h *Handler) issueOneTimeToken(ctx context.Context, uid primitive.ObjectID, purpose string, ttl time.Duration) (string, error) {
+	plain, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := h.OneTimeTokens.Invalidate(ctx, uid, purpose); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = h.OneTimeTokens.Create(ctx, &models.OneTimeToken{
+		User:      uid,
+		Purpose:   purpose,
+		TokenHash: hashToken(plain),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// revokeSessions signs the user out everywhere: refresh tokens stop rotating,
+// access tokens issued so far are rejected by CheckAuth and personal access
+// tokens are deleted.
+func (h *Handler) revokeSessions(ctx context.Context, uid primitive.ObjectID) error {
+	if err := h.RefreshTokens.RevokeUser(ctx, uid); err != nil {
+		return err
+	}
+	if err := h.APITokens.DeleteByUser(ctx, uid); err != nil {
+		return err
+	}
+	return h.revokeAccessTokens(ctx, uid)
+}
+
+// revokeAccessTokens rejects the access tokens uid holds now, so clients must
+// refresh and pick up claims that changed since they were signed.
+func (h *Handler) revokeAccessTokens(ctx context.Context, uid primitive.ObjectID) error {
+	// iat has second precision, so tokens minted later within this second are
+	// rejected too; their clients refresh once more. Truncating instead would
+	// let tokens issued earlier in this second through.
+	now := time.Now()
+	return h.Revocations.RevokeUser(ctx, uid, now, now.Add(h.Config.AccessTokenTTL))
+}
@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"backend/apierror"
+	"backend/models"
+	"backend/store"
+	"backend/totp"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	recoveryCodeCount = 10
+	// recoveryCodeSize is 80 bits, shown as four groups of four characters.
+	recoveryCodeSize = 10
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns the plaintext of a fresh set of recovery codes,
+// which the user sees once, and the hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		s := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16]
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode accepts a recovery code however it was retyped.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// verifySecondFactor reports whether code is a current code from user's
+// authenticator or one of their recovery codes, using it up either way.
+func (h *Handler) verifySecondFactor(ctx context.Context, user *models.User, code string) (bool, error) {
+	if step, ok := totp.Validate(user.TwoFactor.Secret, code, time.Now()); ok {
+		err := h.Users.UseTOTPStep(ctx, user.ID, step)
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	err := h.Users.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// sendTwoFactorChallenge answers a login with the right password for user,
+// who must now enter a code at LoginTwoFactor.
+func (h *Handler) sendTwoFactorChallenge(c *gin.Context, user *models.User) {
+	token, err := h.signChallengeToken(user)
+	if err != nil {
+		serverError(c, err, "Logging in failed, please try again later.")
+		return
+	}
+
+	c.JSON(200, models.TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int(h.Config.TwoFactorChallengeTTL.Seconds()),
+	})
+}
+
+// currentUser loads the authenticated user, aborting and returning nil if
+// that fails.
+func (h *Handler) currentUser(c *gin.Context, ctx context.Context, failure string) *models.User {
+	uid, _ := primitive.ObjectIDFromHex(c.MustGet("userData").(map[string]string)["userId"])
+	user, err := h.Users.FindByID(ctx, uid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errUserNotFound)
+		return nil
+	}
+	if err != nil {
+		serverError(c, err, failure)
+		return nil
+	}
+	return user
+}
+
+func (h *Handler) SetupTwoFactor(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	user := h.currentUser(c, ctx, "Setting up two-factor authentication failed, please try again later.")
+	if user == nil {
+		return
+	}
+	if user.TwoFactor.Enabled {
+		apierror.Abort(c, errTwoFactorEnabled)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		serverError(c, err, "Setting up two-factor authentication failed, please try again later.")
+		return
+	}
+
+	err = h.Users.SetTwoFactor(ctx, user.ID, models.TwoFactor{Secret: secret})
+	if err != nil {
+		serverError(c, err, "Setting up two-factor authentication failed, please try again later.")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, models.TwoFactorSetupResponse{
+		Secret: secret,
+		URI:    totp.URI(h.Config.TwoFactorIssuer, user.Email, secret),
+	})
+}
+
+func (h *Handler) EnableTwoFactor(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	var codeReq models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&codeReq); err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return
+	}
+
+	user := h.currentUser(c, ctx, "Enabling two-factor authentication failed, please try again later.")
+	if user == nil {
+		return
+	}
+	if user.TwoFactor.Enabled {
+		apierror.Abort(c, errTwoFactorEnabled)
+		return
+	}
+	if user.TwoFactor.Secret == "" {
+		apierror.Abort(c, errTwoFactorNotSetUp)
+		return
+	}
+
+	step, ok := totp.Validate(user.TwoFactor.Secret, codeReq.Code, time.Now())
+	if !ok {
+		apierror.Abort(c, errTwoFactorCodeMismatch)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		serverError(c, err, "Enabling two-factor authentication failed, please try again later.")
+		return
+	}
+
+	err = h.Users.SetTwoFactor(ctx, user.ID, models.TwoFactor{
+		Secret:        user.TwoFactor.Secret,
+		Enabled:       true,
+		RecoveryCodes: hashes,
+		LastStep:      step,
+	})
+	if err != nil {
+		serverError(c, err, "Enabling two-factor authentication failed, please try again later.")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor needs the password and a code, so that a stolen session
+// alone cannot remove the second factor.
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	var disableReq models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&disableReq); err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return
+	}
+
+	user := h.currentUser(c, ctx, "Disabling two-factor authentication failed, please try again later.")
+	if user == nil {
+		return
+	}
+	if !user.TwoFactor.Enabled {
+		apierror.Abort(c, errTwoFactorNotEnabled)
+		return
+	}
+
+	// Guesses here count towards the same lockout as logins.
+	account := loginAccount(user.Email)
+	if !h.allowLogin(c, ctx, account) {
+		return
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(disableReq.Password))
+	if err != nil {
+		h.loginFailed(c, ctx, account)
+		apierror.Abort(c, errInvalidCredentials)
+		return
+	}
+
+	ok, err := h.verifySecondFactor(ctx, user, disableReq.Code)
+	if err != nil {
+		serverError(c, err, "Disabling two-factor authentication failed, please try again later.")
+		return
+	}
+	if !ok {
+		h.loginFailed(c, ctx, account)
+		apierror.Abort(c, errInvalidTwoFactorCode)
+		return
+	}
+
+	err = h.Users.SetTwoFactor(ctx, user.ID, models.TwoFactor{})
+	if err != nil {
+		serverError(c, err, "Disabling two-factor authentication failed, please try again later.")
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Two-factor authentication disabled."})
+}
+
+// LoginTwoFactor completes a login that Login answered with a challenge.
+func (h *Handler) LoginTwoFactor(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	var loginReq models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&loginReq); err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return
+	}
+
+	uid, err := h.parseChallengeToken(loginReq.ChallengeToken)
+	if err != nil {
+		apierror.Abort(c, errInvalidChallenge)
+		return
+	}
+
+	user, err := h.Users.FindByID(ctx, uid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errInvalidChallenge)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Logging in failed, please try again later.")
+		return
+	}
+	// Two-factor was disabled since the password was entered.
+	if !user.TwoFactor.Enabled {
+		apierror.Abort(c, errInvalidChallenge)
+		return
+	}
+
+	account := loginAccount(user.Email)
+	if !h.allowLogin(c, ctx, account) {
+		return
+	}
+
+	ok, err := h.verifySecondFactor(ctx, user, loginReq.Code)
+	if err != nil {
+		serverError(c, err, "Logging in failed, please try again later.")
+		return
+	}
+	if !ok {
+		h.loginFailed(c, ctx, account)
+		apierror.Abort(c, errInvalidTwoFactorCode)
+		return
+	}
+	h.loginSucceeded(c, ctx, account)
+
+	response, err := h.authResponse(ctx, user, primitive.NewObjectID())
+	if err != nil {
+		serverError(c, err, "Logging in failed, please try again later.")
+		return
+	}
+
+	h.sendAuthResponse(c, 200, response)
+}
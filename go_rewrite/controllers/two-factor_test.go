@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/models"
+	"backend/store"
+	"backend/totp"
+)
+
+func TestVerifySecondFactorReplay(t *testing.T) {
+	ctx := context.Background()
+	h := &Handler{Users: store.NewMemoryStores().Users}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{Email: "ada@example.com"}
+	if err := h.Users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Users.SetTwoFactor(ctx, user.ID, models.TwoFactor{Secret: secret, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	user, _ = h.Users.FindByID(ctx, user.ID)
+
+	step := totp.Step(time.Now())
+	current, _ := totp.Code(secret, step)
+	previous, _ := totp.Code(secret, step-1)
+
+	if ok, err := h.verifySecondFactor(ctx, user, current); !ok || err != nil {
+		t.Fatalf("first use of the current code = %v, %v, want accepted", ok, err)
+	}
+	if ok, err := h.verifySecondFactor(ctx, user, current); ok || err != nil {
+		t.Errorf("replayed code = %v, %v, want rejected", ok, err)
+	}
+	// An earlier code is still within the skew window, but older than the
+	// one already accepted.
+	if ok, err := h.verifySecondFactor(ctx, user, previous); ok || err != nil {
+		t.Errorf("code of an earlier step = %v, %v, want rejected", ok, err)
+	}
+}
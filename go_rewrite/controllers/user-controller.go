@@ -1,114 +1,253 @@
 package controllers
 
 import (
-	"context"
-	"os"
+	"errors"
 	"time"
 
-	"backend/config"
+	"backend/apierror"
+	"backend/logging"
+	"backend/metrics"
 	"backend/models"
+	"backend/session"
+	"backend/store"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func Signup(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) Signup(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(422, gin.H{"message": "Invalid inputs passed, please check your data."})
+		apierror.Abort(c, apierror.FromBinding(err))
 		return
 	}
 
-	var existingUser models.User
-	err := config.UserCollection.FindOne(ctx, bson.M{"email": user.Email}).Decode(&existingUser)
+	_, err := h.Users.FindByEmail(ctx, user.Email)
 	if err == nil {
-		c.JSON(422, gin.H{"message": "User exists already, please login instead."})
+		apierror.Abort(c, errUserExists)
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), 12)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), h.Config.BcryptCost)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Could not create user, please try again."})
+		serverError(c, err, "Could not create user, please try again.")
 		return
 	}
 	user.Password = string(hashedPassword)
 	user.Blogs = []primitive.ObjectID{}
+	user.EmailVerified = false
+	user.Role = models.RoleAuthor
 
-	result, err := config.UserCollection.InsertOne(ctx, user)
+	err = h.Users.Create(ctx, &user)
+	if errors.Is(err, store.ErrDuplicate) {
+		apierror.Abort(c, errUserExists)
+		return
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Signing up failed, please try again later."})
+		serverError(c, err, "Signing up failed, please try again later.")
 		return
 	}
+	metrics.UsersSignedUp.Inc()
+
+	if err := h.sendVerificationEmail(ctx, &user); err != nil {
+		logging.FromContext(c).Error("sending verification email failed", "error", err)
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userId":    result.InsertedID.(primitive.ObjectID).Hex(),
-		"email":     user.Email,
-		"firstName": user.FirstName,
-		"lastName":  user.LastName,
-		"exp":       time.Now().Add(time.Hour).Unix(),
-	})
-	tokenString, err := token.SignedString([]byte(os.Getenv("TOKEN_SECRET")))
+	response, err := h.authResponse(ctx, &user, primitive.NewObjectID())
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Signing up failed, please try again later."})
+		serverError(c, err, "Signing up failed, please try again later.")
 		return
 	}
 
-	c.JSON(201, models.UserResponse{
-		ID:        result.InsertedID.(primitive.ObjectID).Hex(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Token:     tokenString,
-	})
+	h.sendAuthResponse(c, 201, response)
 }
 
-func Login(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (h *Handler) Login(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
 	defer cancel()
 
 	var loginReq models.LoginRequest
 	if err := c.ShouldBindJSON(&loginReq); err != nil {
-		c.JSON(422, gin.H{"message": "Invalid inputs passed, please check your data."})
+		apierror.Abort(c, apierror.FromBinding(err))
 		return
 	}
 
-	var user models.User
-	err := config.UserCollection.FindOne(ctx, bson.M{"email": loginReq.Email}).Decode(&user)
+	account := loginAccount(loginReq.Email)
+	if !h.allowLogin(c, ctx, account) {
+		return
+	}
+
+	user, err := h.Users.FindByEmail(ctx, loginReq.Email)
+	if errors.Is(err, store.ErrNotFound) {
+		h.loginFailed(c, ctx, account)
+		apierror.Abort(c, errInvalidCredentials)
+		return
+	}
 	if err != nil {
-		c.JSON(403, gin.H{"message": "Invalid credentials, could not log you in."})
+		serverError(c, err, "Logging in failed, please try again later.")
 		return
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginReq.Password))
 	if err != nil {
-		c.JSON(403, gin.H{"message": "Invalid credentials, could not log you in."})
+		h.loginFailed(c, ctx, account)
+		apierror.Abort(c, errInvalidCredentials)
+		return
+	}
+
+	// Failures are only cleared once the second factor is in too, or the
+	// password would reset the lockout on guessing codes.
+	if user.TwoFactor.Enabled {
+		h.sendTwoFactorChallenge(c, user)
+		return
+	}
+	h.loginSucceeded(c, ctx, account)
+
+	response, err := h.authResponse(ctx, user, primitive.NewObjectID())
+	if err != nil {
+		serverError(c, err, "Logging in failed, please try again later.")
+		return
+	}
+
+	h.sendAuthResponse(c, 200, response)
+}
+
+func (h *Handler) Refresh(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	refreshToken, ok := h.bindRefreshToken(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.RefreshTokens.FindByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errInvalidRefreshToken)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Refreshing session failed, please try again later.")
+		return
+	}
+
+	// A consumed token being presented again means it was copied; end the whole session.
+	if token.Used || token.Revoked {
+		h.revokeRefreshFamily(c, ctx, token)
+		apierror.Abort(c, errInvalidRefreshToken)
+		return
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		apierror.Abort(c, errRefreshTokenExpired)
+		return
+	}
+
+	err = h.RefreshTokens.Consume(ctx, token.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		h.revokeRefreshFamily(c, ctx, token)
+		apierror.Abort(c, errInvalidRefreshToken)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Refreshing session failed, please try again later.")
+		return
+	}
+
+	user, err := h.Users.FindByID(ctx, token.User)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errInvalidRefreshToken)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Refreshing session failed, please try again later.")
+		return
+	}
+
+	response, err := h.authResponse(ctx, user, token.Family)
+	if err != nil {
+		serverError(c, err, "Refreshing session failed, please try again later.")
+		return
+	}
+
+	h.sendAuthResponse(c, 200, response)
+}
+
+func (h *Handler) Logout(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	refreshToken, ok := h.bindRefreshToken(c)
+	if !ok {
+		return
+	}
+
+	userData := c.MustGet("userData").(map[string]string)
+	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+
+	token, err := h.RefreshTokens.FindByHash(ctx, hashToken(refreshToken))
+	if err == nil && token.User == uid {
+		err = h.RefreshTokens.RevokeFamily(ctx, token.Family)
+		if err != nil {
+			serverError(c, err, "Logging out failed, please try again later.")
+			return
+		}
+	}
+
+	if jti := userData["jti"]; jti != "" {
+		err = h.Revocations.Revoke(ctx, &models.RevokedToken{
+			JTI:       jti,
+			ExpiresAt: time.Now().Add(h.Config.AccessTokenTTL),
+		})
+		if err != nil {
+			serverError(c, err, "Logging out failed, please try again later.")
+			return
+		}
+	}
+
+	if h.Config.SessionCookies {
+		session.Clear(c, h.Config)
+	}
+
+	c.JSON(200, gin.H{"message": "Logged out!"})
+}
+
+func (h *Handler) SetUserRole(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	userId := c.Param("uid")
+	uid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		apierror.Abort(c, invalidID("user"))
+		return
+	}
+
+	var roleReq models.RoleRequest
+	if err := c.ShouldBindJSON(&roleReq); err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return
+	}
+
+	err = h.Users.SetRole(ctx, uid, roleReq.Role)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Updating role failed, please try again later.")
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userId":    user.ID.Hex(),
-		"email":     user.Email,
-		"firstName": user.FirstName,
-		"lastName":  user.LastName,
-		"exp":       time.Now().Add(time.Hour).Unix(),
-	})
-	tokenString, err := token.SignedString([]byte(os.Getenv("TOKEN_SECRET")))
+	err = h.revokeAccessTokens(ctx, uid)
 	if err != nil {
-		c.JSON(500, gin.H{"message": "Logging in failed, please try again later."})
+		serverError(c, err, "Updating role failed, please try again later.")
 		return
 	}
 
-	c.JSON(200, models.UserResponse{
-		ID:        user.ID.Hex(),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Token:     tokenString,
-	})
+	c.JSON(200, gin.H{"message": "Role updated!"})
 }
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"backend/apierror"
+	"backend/mailer"
+	"backend/models"
+	"backend/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const verificationResendInterval = time.Minute
+
+// sendVerificationEmail issues a new verification token for user and mails the link.
+func (h *Handler) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := h.issueOneTimeToken(ctx, user.ID, models.PurposeVerifyEmail, h.Config.VerifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	link := h.Config.FrontendURL + "/verify-email?token=" + url.QueryEscape(token)
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.FirstName, h.Config.VerifyEmailTTL, link),
+	})
+}
+
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	var verifyReq models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&verifyReq); err != nil {
+		apierror.Abort(c, apierror.FromBinding(err))
+		return
+	}
+
+	token, err := h.OneTimeTokens.Consume(ctx, models.PurposeVerifyEmail, hashToken(verifyReq.Token))
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, apierror.New(http.StatusUnprocessableEntity, codeInvalidOneTimeToken, "Invalid or expired verification token."))
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Verifying email failed, please try again later.")
+		return
+	}
+
+	err = h.Users.SetEmailVerified(ctx, token.User)
+	if err != nil {
+		serverError(c, err, "Verifying email failed, please try again later.")
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Email verified!"})
+}
+
+func (h *Handler) ResendVerification(c *gin.Context) {
+	ctx, cancel := h.requestContext(c)
+	defer cancel()
+
+	userData := c.MustGet("userData").(map[string]string)
+	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+
+	user, err := h.Users.FindByID(ctx, uid)
+	if errors.Is(err, store.ErrNotFound) {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Sending verification email failed, please try again later.")
+		return
+	}
+
+	if user.EmailVerified {
+		apierror.Abort(c, apierror.New(http.StatusConflict, codeEmailAlreadyVerified, "Email is already verified."))
+		return
+	}
+
+	latest, err := h.OneTimeTokens.Latest(ctx, uid, models.PurposeVerifyEmail)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		serverError(c, err, "Sending verification email failed, please try again later.")
+		return
+	}
+	if latest != nil {
+		if wait := time.Until(latest.CreatedAt.Add(verificationResendInterval)); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			apierror.Abort(c, apierror.New(http.StatusTooManyRequests, codeVerificationThrottled, "A verification email was sent recently, please try again later."))
+			return
+		}
+	}
+
+	err = h.sendVerificationEmail(ctx, user)
+	if err != nil {
+		serverError(c, err, "Sending verification email failed, please try again later.")
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Verification email sent!"})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"backend/doctor"
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+)
+
+// runDoctor implements `backend doctor [--fix]`. It prints a JSON report on
+// stdout and exits with 1 while issues remain, or 2 if the check failed.
+func runDoctor(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fix := flags.Bool("fix", false, "repair the issues that can be repaired automatically")
+	timeout := flags.Duration("timeout", 10*time.Minute, "give up after this long")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		log.Print(err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	defer db.Client().Disconnect(ctx)
+
+	report, err := doctor.Check(ctx, db, *fix)
+	if err != nil {
+		log.Printf("doctor: %v", err)
+		return 2
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Printf("doctor: %v", err)
+		return 2
+	}
+
+	if report.Unresolved() {
+		return 1
+	}
+	return 0
+}
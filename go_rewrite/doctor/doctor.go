@@ -0,0 +1,259 @@
+// Package doctor checks the references between users, blogs and comments,
+// which MongoDB does not enforce, and optionally repairs them.
+package doctor
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Issue kinds reported by Check.
+const (
+	// DanglingUserBlog is an entry in a user's blogs that has no blog.
+	DanglingUserBlog = "dangling-user-blog"
+	// MissingUserBlog is a blog absent from its author's blogs.
+	MissingUserBlog = "missing-user-blog"
+	// DanglingBlogComment is an entry in a blog's comments that has no comment.
+	DanglingBlogComment = "dangling-blog-comment"
+	// MissingBlogComment is a comment absent from its blog's comments.
+	MissingBlogComment = "missing-blog-comment"
+	// OrphanedComment is a comment whose blog no longer exists.
+	OrphanedComment = "orphaned-comment"
+	// DanglingParent is a reply whose parent comment no longer exists.
+	DanglingParent = "dangling-parent"
+	// MissingBlogAuthor and MissingCommentAuthor point at deleted users.
+	MissingBlogAuthor    = "missing-blog-author"
+	MissingCommentAuthor = "missing-comment-author"
+	// DuplicateEmail is a user sharing an email address, ignoring case,
+	// with an earlier user.
+	DuplicateEmail = "duplicate-email"
+)
+
+type Issue struct {
+	Kind       string `json:"kind"`
+	Collection string `json:"collection"`
+	ID         string `json:"id"`
+	// Ref is the missing or conflicting document, or the duplicated email.
+	Ref string `json:"ref,omitempty"`
+	// Fixable issues are repaired by --fix; the others need a human.
+	Fixable bool   `json:"fixable"`
+	Fixed   bool   `json:"fixed"`
+	Error   string `json:"error,omitempty"`
+}
+
+type Report struct {
+	CheckedAt time.Time      `json:"checkedAt"`
+	Fix       bool           `json:"fix"`
+	Scanned   map[string]int `json:"scanned"`
+	Issues    []Issue        `json:"issues"`
+	Summary   map[string]int `json:"summary"`
+}
+
+// Unresolved reports whether any issue is still present after the run.
+func (r *Report) Unresolved() bool {
+	for _, issue := range r.Issues {
+		if !issue.Fixed {
+			return true
+		}
+	}
+	return false
+}
+
+type userDoc struct {
+	ID    primitive.ObjectID   `bson:"_id"`
+	Email string               `bson:"email"`
+	Blogs []primitive.ObjectID `bson:"blogs"`
+}
+
+type blogDoc struct {
+	ID       primitive.ObjectID   `bson:"_id"`
+	Author   primitive.ObjectID   `bson:"author"`
+	Comments []primitive.ObjectID `bson:"comments"`
+}
+
+type commentDoc struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	User     primitive.ObjectID `bson:"user"`
+	Blog     primitive.ObjectID `bson:"blog"`
+	ParentID primitive.ObjectID `bson:"parentId,omitempty"`
+}
+
+// Check scans users, blogs and comments and reports every broken reference.
+// With fix set it also repairs the fixable ones, recording the outcome on
+// each issue.
+func Check(ctx context.Context, db *mongo.Database, fix bool) (*Report, error) {
+	users, err := load[userDoc](ctx, db.Collection("users"), bson.M{"_id": 1, "email": 1, "blogs": 1})
+	if err != nil {
+		return nil, err
+	}
+	blogs, err := load[blogDoc](ctx, db.Collection("blogs"), bson.M{"_id": 1, "author": 1, "comments": 1})
+	if err != nil {
+		return nil, err
+	}
+	comments, err := load[commentDoc](ctx, db.Collection("comments"), bson.M{"_id": 1, "user": 1, "blog": 1, "parentId": 1})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		CheckedAt: time.Now().UTC(),
+		Fix:       fix,
+		Scanned:   map[string]int{"users": len(users), "blogs": len(blogs), "comments": len(comments)},
+		Issues:    []Issue{},
+		Summary:   map[string]int{},
+	}
+	r := &repairer{db: db, fix: fix, report: report}
+	r.checkUsers(ctx, users, blogs)
+	r.checkBlogs(ctx, users, blogs, comments)
+	r.checkComments(ctx, users, blogs, comments)
+
+	for _, issue := range report.Issues {
+		report.Summary[issue.Kind]++
+	}
+	return report, nil
+}
+
+func load[T any](ctx context.Context, col *mongo.Collection, projection bson.M) ([]T, error) {
+	cursor, err := col.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]T, 0)
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// repairer records issues and, in fix mode, applies their repair right away.
+type repairer struct {
+	db     *mongo.Database
+	fix    bool
+	report *Report
+}
+
+func (r *repairer) add(issue Issue, repair func() error) {
+	if issue.Fixable && r.fix && repair != nil {
+		if err := repair(); err != nil {
+			issue.Error = err.Error()
+		} else {
+			issue.Fixed = true
+		}
+	}
+	r.report.Issues = append(r.report.Issues, issue)
+}
+
+func (r *repairer) update(ctx context.Context, collection string, id primitive.ObjectID, update bson.M) func() error {
+	return func() error {
+		_, err := r.db.Collection(collection).UpdateOne(ctx, bson.M{"_id": id}, update)
+		return err
+	}
+}
+
+func (r *repairer) checkUsers(ctx context.Context, users []userDoc, blogs []blogDoc) {
+	blogIDs := make(map[primitive.ObjectID]bool, len(blogs))
+	for _, b := range blogs {
+		blogIDs[b.ID] = true
+	}
+
+	seenEmails := make(map[string]primitive.ObjectID, len(users))
+	for _, u := range users {
+		for _, bid := range u.Blogs {
+			if !blogIDs[bid] {
+				r.add(Issue{Kind: DanglingUserBlog, Collection: "users", ID: u.ID.Hex(), Ref: bid.Hex(), Fixable: true},
+					r.update(ctx, "users", u.ID, bson.M{"$pull": bson.M{"blogs": bid}}))
+			}
+		}
+
+		email := strings.ToLower(strings.TrimSpace(u.Email))
+		if first, ok := seenEmails[email]; ok {
+			r.add(Issue{Kind: DuplicateEmail, Collection: "users", ID: u.ID.Hex(), Ref: first.Hex() + " " + email}, nil)
+			continue
+		}
+		seenEmails[email] = u.ID
+	}
+}
+
+func (r *repairer) checkBlogs(ctx context.Context, users []userDoc, blogs []blogDoc, comments []commentDoc) {
+	userBlogs := make(map[primitive.ObjectID]map[primitive.ObjectID]bool, len(users))
+	for _, u := range users {
+		owned := make(map[primitive.ObjectID]bool, len(u.Blogs))
+		for _, bid := range u.Blogs {
+			owned[bid] = true
+		}
+		userBlogs[u.ID] = owned
+	}
+	commentIDs := make(map[primitive.ObjectID]bool, len(comments))
+	for _, c := range comments {
+		commentIDs[c.ID] = true
+	}
+
+	for _, b := range blogs {
+		owned, authorExists := userBlogs[b.Author]
+		switch {
+		case !authorExists:
+			r.add(Issue{Kind: MissingBlogAuthor, Collection: "blogs", ID: b.ID.Hex(), Ref: b.Author.Hex()}, nil)
+		case !owned[b.ID]:
+			r.add(Issue{Kind: MissingUserBlog, Collection: "users", ID: b.Author.Hex(), Ref: b.ID.Hex(), Fixable: true},
+				r.update(ctx, "users", b.Author, bson.M{"$addToSet": bson.M{"blogs": b.ID}}))
+		}
+
+		for _, cid := range b.Comments {
+			if !commentIDs[cid] {
+				r.add(Issue{Kind: DanglingBlogComment, Collection: "blogs", ID: b.ID.Hex(), Ref: cid.Hex(), Fixable: true},
+					r.update(ctx, "blogs", b.ID, bson.M{"$pull": bson.M{"comments": cid}}))
+			}
+		}
+	}
+}
+
+func (r *repairer) checkComments(ctx context.Context, users []userDoc, blogs []blogDoc, comments []commentDoc) {
+	userIDs := make(map[primitive.ObjectID]bool, len(users))
+	for _, u := range users {
+		userIDs[u.ID] = true
+	}
+	blogComments := make(map[primitive.ObjectID]map[primitive.ObjectID]bool, len(blogs))
+	for _, b := range blogs {
+		listed := make(map[primitive.ObjectID]bool, len(b.Comments))
+		for _, cid := range b.Comments {
+			listed[cid] = true
+		}
+		blogComments[b.ID] = listed
+	}
+	commentIDs := make(map[primitive.ObjectID]bool, len(comments))
+	for _, c := range comments {
+		commentIDs[c.ID] = true
+	}
+
+	col := r.db.Collection("comments")
+	for _, c := range comments {
+		listed, blogExists := blogComments[c.Blog]
+		if !blogExists {
+			r.add(Issue{Kind: OrphanedComment, Collection: "comments", ID: c.ID.Hex(), Ref: c.Blog.Hex(), Fixable: true},
+				func() error {
+					_, err := col.DeleteOne(ctx, bson.M{"_id": c.ID})
+					return err
+				})
+			continue
+		}
+
+		if !listed[c.ID] {
+			r.add(Issue{Kind: MissingBlogComment, Collection: "blogs", ID: c.Blog.Hex(), Ref: c.ID.Hex(), Fixable: true},
+				r.update(ctx, "blogs", c.Blog, bson.M{"$addToSet": bson.M{"comments": c.ID}}))
+		}
+		// Replies to a vanished parent are promoted to the top level.
+		if !c.ParentID.IsZero() && !commentIDs[c.ParentID] {
+			r.add(Issue{Kind: DanglingParent, Collection: "comments", ID: c.ID.Hex(), Ref: c.ParentID.Hex(), Fixable: true},
+				r.update(ctx, "comments", c.ID, bson.M{"$unset": bson.M{"parentId": ""}, "$set": bson.M{"depth": 0}}))
+		}
+		if !userIDs[c.User] {
+			r.add(Issue{Kind: MissingCommentAuthor, Collection: "comments", ID: c.ID.Hex(), Ref: c.User.Hex()}, nil)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JWK is the public half of a key as RFC 7517 describes it.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// N and E are set for RSA keys.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve and X are set for Ed25519 keys.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, served so that other services can verify
+// tokens without sharing a secret.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns every published key, including the next one before it signs.
+func (k *Keyring) JWKS() JWKS {
+	keys := k.Keys()
+	set := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		jwk := JWK{ID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+		switch public := key.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType, jwk.N, jwk.E = "RSA", encode(public.N.Bytes()), encode(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType, jwk.Curve, jwk.X = "OKP", "Ed25519", encode(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// thumbprintInput is the JSON RFC 7638 hashes into a key's thumbprint: the
+// required members in lexicographic order, without whitespace.
+func thumbprintInput(public crypto.PublicKey) []byte {
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		e := encode(big.NewInt(int64(public.E)).Bytes())
+		return fmt.Appendf(nil, `{"e":"%s","kty":"RSA","n":"%s"}`, e, encode(public.N.Bytes()))
+	case ed25519.PublicKey:
+		return fmt.Appendf(nil, `{"crv":"Ed25519","kty":"OKP","x":"%s"}`, encode(public))
+	}
+	panic(fmt.Sprintf("keyring: unsupported public key %T", public))
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
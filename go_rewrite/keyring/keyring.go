@@ -0,0 +1,299 @@
+// Package keyring holds the asymmetric keys access tokens are signed with.
+// Keys are rotated on a schedule: each new key is published in the JWKS ahead
+// of signing with it, and old keys keep verifying until the tokens they signed
+// have expired, so rotation never logs anyone out.
+package keyring
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms.
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// Algorithms lists every algorithm tokens may be signed with, for verifiers.
+var Algorithms = []string{RS256, EdDSA}
+
+const (
+	// PublishAhead is how long a new key is in the JWKS before it signs
+	// anything, which must exceed how long verifiers cache the JWKS.
+	PublishAhead = time.Hour
+	rsaKeyBits   = 2048
+)
+
+// ErrNoSigningKey is returned while no key has become active yet.
+var ErrNoSigningKey = errors.New("keyring: no active signing key")
+
+// Policy configures which keys the keyring creates and how long it keeps them.
+type Policy struct {
+	Algorithm string
+	// RotateEvery is how long each key signs before the next takes over. Zero
+	// keeps the current key until the algorithm changes.
+	RotateEvery time.Duration
+	// TokenTTL is the longest lifetime of a signed token, for which a replaced
+	// key keeps verifying.
+	TokenTTL time.Duration
+}
+
+// Key is a signing key and its place in the rotation.
+type Key struct {
+	ID          string
+	Generation  int
+	Algorithm   string
+	Private     crypto.Signer
+	CreatedAt   time.Time
+	ActivatesAt time.Time
+}
+
+func (k *Key) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	if k.Algorithm == RS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// Keyring signs tokens with the current key and verifies them with any key
+// that is still published. It is safe for concurrent use.
+type Keyring struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+
+	mu   sync.RWMutex
+	keys []*Key // by generation
+}
+
+// Open returns a keyring over store, creating the first key when store has
+// none, so that it can sign right away.
+func Open(ctx context.Context, store Store, policy Policy) (*Keyring, error) {
+	if policy.Algorithm != RS256 && policy.Algorithm != EdDSA {
+		return nil, fmt.Errorf("keyring: unsupported algorithm %q", policy.Algorithm)
+	}
+
+	k := &Keyring{store: store, policy: policy, now: time.Now}
+	if err := k.Rotate(ctx); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Rotate creates the next key once the current one is due for replacement,
+// forgets keys no token can still be signed with, and reloads the keys from
+// the store so that every instance sees the same ones. Instances sharing a
+// store may rotate concurrently; only one of them creates each key.
+func (k *Keyring) Rotate(ctx context.Context) error {
+	records, err := k.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := decodeKeys(records)
+	if err != nil {
+		return err
+	}
+
+	now := k.now()
+	if next := k.nextActivation(keys, now); !next.IsZero() {
+		generation := 1
+		if len(keys) > 0 {
+			generation = keys[len(keys)-1].Generation + 1
+		}
+		record, err := newRecord(k.policy.Algorithm, generation, now, next)
+		if err != nil {
+			return err
+		}
+
+		err = k.store.Create(ctx, record)
+		if errors.Is(err, ErrGenerationTaken) {
+			// Another instance rotated first; pick up its key instead.
+			return k.Rotate(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		key, err := record.decode()
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	keys, retired := k.split(keys, now)
+	if len(retired) > 0 {
+		if err := k.store.Delete(ctx, retired); err != nil {
+			return err
+		}
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// nextActivation returns when a new key should start signing, or the zero
+// time when no new key is needed yet.
+func (k *Keyring) nextActivation(keys []*Key, now time.Time) time.Time {
+	if len(keys) == 0 {
+		return now
+	}
+	latest := keys[len(keys)-1]
+	if latest.ActivatesAt.After(now) {
+		return time.Time{}
+	}
+	if latest.Algorithm != k.policy.Algorithm {
+		return now.Add(PublishAhead)
+	}
+	if k.policy.RotateEvery <= 0 {
+		return time.Time{}
+	}
+
+	due := latest.ActivatesAt.Add(k.policy.RotateEvery)
+	if now.Before(due.Add(-PublishAhead)) {
+		return time.Time{}
+	}
+	// A rotation that is overdue, say after downtime, is still published
+	// ahead rather than activated at once.
+	return maxTime(due, now.Add(PublishAhead))
+}
+
+// split separates the keys still needed from the IDs of those replaced for
+// longer than a token lives.
+func (k *Keyring) split(keys []*Key, now time.Time) ([]*Key, []string) {
+	var retired []string
+	for len(keys) > 1 && !now.Before(keys[1].ActivatesAt.Add(k.policy.TokenTTL)) {
+		retired = append(retired, keys[0].ID)
+		keys = keys[1:]
+	}
+	return keys, retired
+}
+
+// Current returns the key new tokens are signed with: the newest active one.
+func (k *Keyring) Current() (*Key, error) {
+	now := k.now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if !k.keys[i].ActivatesAt.After(now) {
+			return k.keys[i], nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// Lookup returns the key with kid, including keys not yet signing, since
+// another instance's clock may be slightly ahead.
+func (k *Keyring) Lookup(kid string) (*Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	i := slices.IndexFunc(k.keys, func(key *Key) bool { return key.ID == kid })
+	if i < 0 {
+		return nil, false
+	}
+	return k.keys[i], true
+}
+
+// Keys returns every published key, oldest first.
+func (k *Keyring) Keys() []*Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return slices.Clone(k.keys)
+}
+
+// Sign signs claims with the current key, naming it in the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	key, err := k.Current()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc finds the key a token names in its kid header, for jwt.Parse.
+func (k *Keyring) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("keyring: unknown key %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("keyring: key %q is not for %s", kid, token.Method.Alg())
+	}
+	return key.Public(), nil
+}
+
+// newRecord generates a key for algorithm. Its ID is the key's RFC 7638
+// thumbprint, which verifiers can recompute from the JWKS.
+func newRecord(algorithm string, generation int, now, activatesAt time.Time) (*Record, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("keyring: unsupported algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(thumbprintInput(private.Public()))
+	return &Record{
+		ID:          base64.RawURLEncoding.EncodeToString(sum[:]),
+		Generation:  generation,
+		Algorithm:   algorithm,
+		PrivateKey:  der,
+		CreatedAt:   now.UTC(),
+		ActivatesAt: activatesAt.UTC(),
+	}, nil
+}
+
+func decodeKeys(records []Record) ([]*Key, error) {
+	keys := make([]*Key, 0, len(records))
+	for i := range records {
+		key, err := records[i].decode()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b *Key) int { return a.Generation - b.Generation })
+	return keys, nil
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,184 @@
+package keyring
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// clock is a settable time source for Keyring.now.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// openAt opens a keyring over store whose clock is c.
+func openAt(t *testing.T, store Store, policy Policy, c *clock) *Keyring {
+	t.Helper()
+	k := &Keyring{store: store, policy: policy, now: c.Now}
+	if err := k.Rotate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestNextActivation(t *testing.T) {
+	const rotate = 24 * time.Hour
+	active := &Key{Generation: 1, Algorithm: EdDSA, ActivatesAt: epoch}
+	due := epoch.Add(rotate)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		keys   []*Key
+		now    time.Time
+		want   time.Time
+	}{
+		{"no keys", Policy{Algorithm: EdDSA}, nil, epoch, epoch},
+		{"latest not active yet", Policy{Algorithm: EdDSA, RotateEvery: rotate}, []*Key{active, {Generation: 2, Algorithm: EdDSA, ActivatesAt: due}}, due.Add(-time.Minute), time.Time{}},
+		{"algorithm changed", Policy{Algorithm: RS256, RotateEvery: rotate}, []*Key{active}, epoch.Add(time.Minute), epoch.Add(time.Minute + PublishAhead)},
+		{"never rotates", Policy{Algorithm: EdDSA}, []*Key{active}, epoch.Add(365 * rotate), time.Time{}},
+		{"not due", Policy{Algorithm: EdDSA, RotateEvery: rotate}, []*Key{active}, due.Add(-PublishAhead - time.Nanosecond), time.Time{}},
+		{"publish window opens", Policy{Algorithm: EdDSA, RotateEvery: rotate}, []*Key{active}, due.Add(-PublishAhead), due},
+		{"overdue", Policy{Algorithm: EdDSA, RotateEvery: rotate}, []*Key{active}, due.Add(time.Hour), due.Add(time.Hour + PublishAhead)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &Keyring{policy: tt.policy}
+			if got := k.nextActivation(tt.keys, tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextActivation = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	const ttl = 15 * time.Minute
+	keys := []*Key{
+		{ID: "a", ActivatesAt: epoch},
+		{ID: "b", ActivatesAt: epoch.Add(time.Hour)},
+		{ID: "c", ActivatesAt: epoch.Add(2 * time.Hour)},
+	}
+	k := &Keyring{policy: Policy{TokenTTL: ttl}}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		kept    int
+		retired []string
+	}{
+		{"tokens of a may be live", epoch.Add(time.Hour + ttl - time.Nanosecond), 3, nil},
+		{"a has expired", epoch.Add(time.Hour + ttl), 2, []string{"a"}},
+		{"a and b have expired", epoch.Add(2*time.Hour + ttl), 1, []string{"a", "b"}},
+		{"the last key is kept", epoch.Add(1000 * time.Hour), 1, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, retired := k.split(keys, tt.now)
+			if len(kept) != tt.kept || kept[len(kept)-1].ID != "c" {
+				t.Errorf("kept %d keys ending in %s, want %d ending in c", len(kept), kept[len(kept)-1].ID, tt.kept)
+			}
+			if len(retired) != len(tt.retired) {
+				t.Fatalf("retired = %v, want %v", retired, tt.retired)
+			}
+			for i := range retired {
+				if retired[i] != tt.retired[i] {
+					t.Errorf("retired = %v, want %v", retired, tt.retired)
+				}
+			}
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	ctx := context.Background()
+	policy := Policy{Algorithm: EdDSA, RotateEvery: 24 * time.Hour, TokenTTL: 15 * time.Minute}
+	c := &clock{now: epoch}
+	store := NewMemoryStore()
+	k := openAt(t, store, policy, c)
+
+	first, err := k.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The next key is published ahead of signing.
+	c.Advance(policy.RotateEvery - PublishAhead)
+	if err := k.Rotate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if keys := k.Keys(); len(keys) != 2 || !keys[1].ActivatesAt.Equal(epoch.Add(policy.RotateEvery)) {
+		t.Fatalf("keys = %v, want a second one activating at %v", keys, epoch.Add(policy.RotateEvery))
+	}
+	if current, _ := k.Current(); current.ID != first.ID {
+		t.Error("the published key signs before it activates")
+	}
+
+	c.Advance(PublishAhead)
+	second, err := k.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID == first.ID {
+		t.Fatal("the published key does not sign once active")
+	}
+
+	// The replaced key verifies until its tokens have expired.
+	c.Advance(policy.TokenTTL - time.Nanosecond)
+	if err := k.Rotate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := k.Lookup(first.ID); !ok {
+		t.Error("the replaced key was retired while its tokens may be live")
+	}
+	c.Advance(time.Nanosecond)
+	if err := k.Rotate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := k.Lookup(first.ID); ok {
+		t.Error("the replaced key was kept after its tokens expired")
+	}
+	if records, _ := store.List(ctx); len(records) != 1 || records[0].ID != second.ID {
+		t.Errorf("store holds %d keys, want only %s", len(records), second.ID)
+	}
+}
+
+// staleStore lists no keys the first time, as if another instance created
+// one between List and Create.
+type staleStore struct {
+	Store
+	listed bool
+}
+
+func (s *staleStore) List(ctx context.Context) ([]Record, error) {
+	if !s.listed {
+		s.listed = true
+		return nil, nil
+	}
+	return s.Store.List(ctx)
+}
+
+func TestRotateGenerationTaken(t *testing.T) {
+	ctx := context.Background()
+	policy := Policy{Algorithm: EdDSA, RotateEvery: 24 * time.Hour, TokenTTL: 15 * time.Minute}
+	c := &clock{now: epoch}
+	shared := NewMemoryStore()
+
+	winner := openAt(t, shared, policy, c)
+	loser := openAt(t, &staleStore{Store: shared}, policy, c)
+
+	want, _ := winner.Current()
+	got, err := loser.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != want.ID {
+		t.Errorf("the losing instance signs with %s, want the winner's %s", got.ID, want.ID)
+	}
+	if records, _ := shared.List(ctx); len(records) != 1 {
+		t.Errorf("store holds %d keys, want 1", len(records))
+	}
+}
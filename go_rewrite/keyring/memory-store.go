@@ -0,0 +1,44 @@
+package keyring
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryStore keeps keys in the process, so tokens only verify on the
+// instance that signed them and every restart invalidates them. It is meant
+// for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.records), nil
+}
+
+func (s *MemoryStore) Create(ctx context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.records, func(r Record) bool { return r.Generation == record.Generation }) {
+		return ErrGenerationTaken
+	}
+	s.records = append(s.records, *record)
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = slices.DeleteFunc(s.records, func(r Record) bool { return slices.Contains(ids, r.ID) })
+	return nil
+}
@@ -0,0 +1,44 @@
+package keyring
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoStore keeps keys in the signingKeys collection, whose unique index on
+// generation, from the signing-keys migration, lets only one instance create
+// each key.
+type MongoStore struct {
+	col *mongo.Collection
+}
+
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{col: db.Collection("signingKeys")}
+}
+
+func (s *MongoStore) List(ctx context.Context) ([]Record, error) {
+	cursor, err := s.col.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0)
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *MongoStore) Create(ctx context.Context, record *Record) error {
+	_, err := s.col.InsertOne(ctx, record)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrGenerationTaken
+	}
+	return err
+}
+
+func (s *MongoStore) Delete(ctx context.Context, ids []string) error {
+	_, err := s.col.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
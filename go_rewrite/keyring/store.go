@@ -0,0 +1,54 @@
+package keyring
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrGenerationTaken is returned by Store.Create when another key already
+// has the generation, meaning another instance rotated first.
+var ErrGenerationTaken = errors.New("keyring: generation already taken")
+
+// Record is a key as stored, with its private half in PKCS #8 form. Anyone
+// who can read the records can sign tokens, so the store must be protected
+// like the rest of the database.
+type Record struct {
+	ID          string    `bson:"_id"`
+	Generation  int       `bson:"generation"`
+	Algorithm   string    `bson:"algorithm"`
+	PrivateKey  []byte    `bson:"privateKey"`
+	CreatedAt   time.Time `bson:"createdAt"`
+	ActivatesAt time.Time `bson:"activatesAt"`
+}
+
+func (r *Record) decode() (*Key, error) {
+	parsed, err := x509.ParsePKCS8PrivateKey(r.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("keyring: key %s: %w", r.ID, err)
+	}
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("keyring: key %s cannot sign", r.ID)
+	}
+	return &Key{
+		ID:          r.ID,
+		Generation:  r.Generation,
+		Algorithm:   r.Algorithm,
+		Private:     private,
+		CreatedAt:   r.CreatedAt,
+		ActivatesAt: r.ActivatesAt,
+	}, nil
+}
+
+// Store persists the keys shared by every instance.
+type Store interface {
+	List(ctx context.Context) ([]Record, error)
+	// Create returns ErrGenerationTaken when a key with the same generation
+	// exists.
+	Create(ctx context.Context, record *Record) error
+	Delete(ctx context.Context, ids []string) error
+}
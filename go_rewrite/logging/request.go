@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions, so a proxy's ID
+// is kept and clients can quote it when reporting a problem.
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	requestIDKey = "requestId"
+	// maxRequestIDLength bounds IDs taken from clients, which end up in
+	// every log line of the request.
+	maxRequestIDLength = 128
+)
+
+// RequestID adopts the incoming X-Request-ID when it looks sane, or makes a
+// new one, and echoes it on the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestIDFrom returns the ID RequestID assigned to the request.
+func RequestIDFrom(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// FromContext returns the default logger annotated with the request ID and,
+// once CheckAuth has run, the user ID.
+func FromContext(c *gin.Context) *slog.Logger {
+	logger := slog.Default().With("request_id", RequestIDFrom(c))
+	if userData, ok := c.Get("userData"); ok {
+		logger = logger.With("user_id", userData.(map[string]string)["userId"])
+	}
+	return logger
+}
+
+// AccessLog writes one record per request once it has been handled. It must
+// run after RequestID.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+		FromContext(c).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
@@ -2,59 +2,171 @@ package main
 
 import (
 	"backend/config"
+	"backend/controllers"
+	"backend/keyring"
+	"backend/logging"
+	"backend/mailer"
+	"backend/migrate"
+	"backend/ratelimit"
 	"backend/routes"
+	"backend/scheduler"
+	"backend/search"
+	"backend/store"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 )
 
+// keyRotationCheck is how often the signing keys are checked for rotation and
+// reloaded to pick up keys other instances created.
+const keyRotationCheck = time.Minute
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:]))
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:]))
+		}
+	}
 
-	tokenSecret := os.Getenv("TOKEN_SECRET")
-	if tokenSecret == "" {
-		log.Fatal("TOKEN_SECRET environment variable not set")
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
 
-	dbURI := os.Getenv("db")
-	if dbURI == "" {
-		log.Fatal("db environment variable not set")
+	logger, err := logging.New(os.Stdout, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		log.Fatal(err)
 	}
+	slog.SetDefault(logger)
 
-	err := config.ConnectDB(dbURI)
+	// ctx is cancelled on SIGINT or SIGTERM, which starts the shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := config.ConnectDBRetrying(ctx, cfg.DatabaseURI, cfg.DBTimeout, cfg.ConnectRetryFor)
 	if err != nil {
-		log.Fatal("Could not connect to database")
+		fatal("Could not connect to database", err)
 	}
 
-	router := gin.Default()
+	var mail mailer.Mailer = &mailer.LogMailer{Dir: cfg.MailDir}
+	if cfg.SMTPAddr != "" {
+		mail = &mailer.SMTPMailer{
+			Addr:     cfg.SMTPAddr,
+			From:     cfg.SMTPFrom,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+		}
+	}
 
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"https://aryan7901.github.io", "http://localhost:3000"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	// auto_migrate=false leaves schema changes to `backend migrate up`.
+	if cfg.AutoMigrate {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		applied, err := migrate.New(db).Up(ctx, 0)
+		cancel()
+		for _, m := range applied {
+			slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+		if errors.Is(err, migrate.ErrLocked) {
+			slog.Warn("Another instance is migrating the database, starting anyway")
+		} else if err != nil {
+			fatal("Could not migrate the database", err)
+		}
+	}
 
-	routes.UserRoutes(router)
-	routes.BlogRoutes(router)
+	stores := store.NewMongoStores(db)
 
-	router.Use(func(c *gin.Context) {
-		c.JSON(404, gin.H{"message": "Could not find this route."})
-	})
+	var searcher search.Searcher = search.NewMongoSearcher(db)
+	if cfg.SearchBackend == "memory" {
+		index := search.NewMemorySearcher()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		err = index.Rebuild(ctx, stores.Blogs, stores.Comments)
+		cancel()
+		if err != nil {
+			fatal("Could not build search index", err)
+		}
+		searcher = index
+	}
 
-	router.Use(func(c *gin.Context) {
-		c.Next()
-	})
+	var limits ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "mongo" {
+		limits = ratelimit.NewMongoStore(db)
+	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "5000"
+	keysCtx, cancel := context.WithTimeout(ctx, cfg.DBTimeout)
+	keys, err := keyring.Open(keysCtx, keyring.NewMongoStore(db), cfg.KeyPolicy())
+	cancel()
+	if err != nil {
+		fatal("Could not load signing keys", err)
 	}
 
-	log.Printf("Server running on port %s", port)
-	router.Run(":" + port)
+	handler := controllers.NewHandler(cfg, stores, keys, mail, searcher, limits)
+
+	go scheduler.RunPublisher(ctx, stores.Blogs, cfg.PublishInterval, cfg.DBTimeout)
+	go scheduler.RunKeyRotation(ctx, keys, keyRotationCheck)
+	server := &http.Server{
+		Addr:    ":" + strconv.Itoa(cfg.Port),
+		Handler: routes.NewRouter(handler),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server running", "port", cfg.Port)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	failed := false
+	select {
+	case err := <-serveErr:
+		slog.Error("Server stopped", "error", err)
+		failed = true
+	case <-ctx.Done():
+		slog.Info("Shutting down, waiting for in-flight requests")
+	}
+	stop()
+
+	// Fail readiness first and keep serving while load balancers notice. A
+	// second signal now kills the process, as stop restored the default.
+	handler.Drain()
+	if !failed {
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Could not finish in-flight requests", "error", err)
+		failed = true
+	}
+	// Password reset emails may still be on their way.
+	handler.Wait()
+	if err := db.Client().Disconnect(shutdownCtx); err != nil {
+		slog.Error("Could not disconnect from database", "error", err)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// fatal logs err and exits, like log.Fatal but through the structured logger.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
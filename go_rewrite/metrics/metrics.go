@@ -0,0 +1,106 @@
+// Package metrics defines the Prometheus metrics the server exposes on
+// /metrics. Collectors are package-level so any package can record to them
+// without threading a registry through every constructor.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "backend"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time spent handling HTTP requests, by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being handled.",
+	})
+
+	mongoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_duration_seconds",
+		Help:      "Time spent on MongoDB commands, by collection and command.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"collection", "operation"})
+
+	mongoErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_errors_total",
+		Help:      "MongoDB commands that failed, by collection and command.",
+	}, []string{"collection", "operation"})
+
+	// AuthFailures counts requests CheckAuth rejected, by reason.
+	AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Requests rejected by authentication, by reason.",
+	}, []string{"reason"})
+
+	// RateLimited counts requests rejected by a rate limit, by limit name.
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by rate limits and login lockouts, by limit.",
+	}, []string{"limit"})
+
+	UsersSignedUp = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "users_signed_up_total",
+		Help:      "Users who signed up.",
+	})
+
+	BlogsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blogs_created_total",
+		Help:      "Blogs created.",
+	})
+
+	CommentsPosted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "comments_posted_total",
+		Help:      "Comments and replies posted.",
+	})
+)
+
+// Auth failure reasons.
+const (
+	AuthMissingToken = "missing_token"
+	AuthInvalidToken = "invalid_token"
+	AuthRevokedToken = "revoked_token"
+	AuthError        = "error"
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, httpInFlight,
+		mongoDuration, mongoErrors,
+		AuthFailures, RateLimited,
+		UsersSignedUp, BlogsCreated, CommentsPosted,
+	)
+}
+
+// Handler serves every metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
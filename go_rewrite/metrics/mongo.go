@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// noCollection labels commands such as hello or commitTransaction that do not
+// address a collection.
+const noCollection = "none"
+
+// CommandMonitor returns a monitor for mongo client options that records the
+// duration of every command and counts the failed ones.
+func CommandMonitor() *event.CommandMonitor {
+	// Finished events do not carry the command, so the collection is
+	// remembered from the started event.
+	var collections sync.Map
+
+	finished := func(requestID int64, operation string, seconds float64, failed bool) {
+		collection := noCollection
+		if value, ok := collections.LoadAndDelete(requestID); ok {
+			collection = value.(string)
+		}
+		mongoDuration.WithLabelValues(collection, operation).Observe(seconds)
+		if failed {
+			mongoErrors.WithLabelValues(collection, operation).Inc()
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			collections.Store(e.RequestID, commandCollection(e))
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finished(e.RequestID, e.CommandName, e.Duration.Seconds(), false)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finished(e.RequestID, e.CommandName, e.Duration.Seconds(), true)
+		},
+	}
+}
+
+// commandCollection reads the collection a command addresses. Most commands
+// name it as their first value; getMore names it separately.
+func commandCollection(e *event.CommandStartedEvent) string {
+	if e.CommandName == "getMore" {
+		if collection, ok := e.Command.Lookup("collection").StringValueOK(); ok {
+			return collection
+		}
+	}
+	if collection, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+		return collection
+	}
+	return noCollection
+}
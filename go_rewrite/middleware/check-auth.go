@@ -1,64 +1,228 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
-	"os"
 	"strings"
+	"time"
+
+	"backend/apierror"
+	"backend/config"
+	"backend/keyring"
+	"backend/logging"
+	"backend/metrics"
+	"backend/models"
+	"backend/session"
+	"backend/store"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errNoToken          = errors.New("no bearer token")
+	errInvalidToken     = errors.New("invalid bearer token")
+	errRevokedToken     = errors.New("revoked bearer token")
+	errAPITokenRejected = errors.New("API token on a session-only route")
+)
+
+const (
+	// clockSkew is how far the clocks of the instances signing and verifying a
+	// token may drift apart.
+	clockSkew = 30 * time.Second
+	// lastUsedGranularity bounds how often an API token's last use is
+	// written, so busy scripts do not write on every request.
+	lastUsedGranularity = time.Minute
+)
+
+var (
+	errUnauthenticated   = apierror.New(http.StatusUnauthorized, apierror.CodeUnauthenticated, "Authentication failed!")
+	errAPITokenForbidden = apierror.New(http.StatusForbidden, "api_token_not_allowed", "API tokens cannot be used here, please login instead.")
 )
 
-func CheckAuth() gin.HandlerFunc {
+// APITokens lets CheckAuth accept personal access tokens besides JWTs. Their
+// owner is loaded on every request, so a changed role applies at once.
+type APITokens struct {
+	Tokens store.APITokenStore
+	Users  store.UserStore
+}
+
+// authenticate verifies the request's bearer token, or its session cookie
+// when it has no Authorization header, against keys, including its
+// issuer, audience and lifetime, and returns the userData handlers read from
+// the context. Personal access tokens are checked against apiTokens instead,
+// and rejected when it is nil. Store failures are returned as-is so callers
+// can tell them apart from bad credentials.
+func authenticate(c *gin.Context, cfg *config.Config, keys *keyring.Keyring, revocations store.RevocationStore, apiTokens *APITokens) (map[string]string, error) {
+	tokenString := session.AccessToken(c, cfg)
+	if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+		_, tokenString, _ = strings.Cut(authHeader, " ")
+		if tokenString == "" {
+			return nil, errInvalidToken
+		}
+	}
+	if tokenString == "" {
+		return nil, errNoToken
+	}
+	if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+		if apiTokens == nil {
+			return nil, errAPITokenRejected
+		}
+		return authenticateAPIToken(c, cfg, apiTokens, tokenString)
+	}
+
+	token, err := jwt.Parse(tokenString, keys.Keyfunc,
+		jwt.WithValidMethods(keyring.Algorithms),
+		jwt.WithIssuer(cfg.TokenIssuer),
+		jwt.WithAudience(cfg.TokenAudience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil || !token.Valid {
+		return nil, errInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errInvalidToken
+	}
+
+	userId, _ := claims["userId"].(string)
+	jti, _ := claims["jti"].(string)
+	uid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil || jti == "" {
+		return nil, errInvalidToken
+	}
+	if sub, _ := claims.GetSubject(); sub != userId {
+		return nil, errInvalidToken
+	}
+	var issuedAt time.Time
+	if iat, _ := claims.GetIssuedAt(); iat != nil {
+		issuedAt = iat.Time
+	}
+
+	ctx, cancel := requestContext(c, cfg)
+	revoked, err := revocations.IsRevoked(ctx, jti, uid, issuedAt)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errRevokedToken
+	}
+
+	email, _ := claims["email"].(string)
+	firstName, _ := claims["firstName"].(string)
+	lastName, _ := claims["lastName"].(string)
+	role, _ := claims["role"].(string)
+
+	return map[string]string{
+		"userId":    userId,
+		"email":     email,
+		"firstName": firstName,
+		"lastName":  lastName,
+		"role":      role,
+		"jti":       jti,
+	}, nil
+}
+
+// authenticateAPIToken returns the userData of a personal access token's
+// owner, with the token's ID and space-separated scopes for RequireScope.
+func authenticateAPIToken(c *gin.Context, cfg *config.Config, apiTokens *APITokens, tokenString string) (map[string]string, error) {
+	ctx, cancel := requestContext(c, cfg)
+	defer cancel()
+
+	token, err := apiTokens.Tokens.FindByHash(ctx, models.HashAPIToken(tokenString))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := apiTokens.Users.FindByID(ctx, token.User)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedGranularity {
+		if err := apiTokens.Tokens.MarkUsed(ctx, token.ID, now); err != nil {
+			logging.FromContext(c).Error("recording API token use failed", "token_id", token.ID.Hex(), "error", err)
+		}
+	}
+
+	return map[string]string{
+		"userId":     user.ID.Hex(),
+		"email":      user.Email,
+		"firstName":  user.FirstName,
+		"lastName":   user.LastName,
+		"role":       models.EffectiveRole(user.Role),
+		"apiTokenId": token.ID.Hex(),
+		"scopes":     strings.Join(token.Scopes, " "),
+	}, nil
+}
+
+// CheckAuth validates the bearer token or session cookie against the keys in keys and rejects access tokens that have been
+// revoked through revocations, either individually or for the whole user.
+// With apiTokens it also accepts personal access tokens, whose routes must
+// then each RequireScope; without it they are refused with 403.
+func CheckAuth(cfg *config.Config, keys *keyring.Keyring, revocations store.RevocationStore, apiTokens *APITokens) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method == "OPTIONS" {
 			c.Next()
 			return
 		}
 
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusForbidden, gin.H{"message": "Authentication failed!"})
-			c.Abort()
-			return
+		userData, err := authenticate(c, cfg, keys, revocations, apiTokens)
+		if err != nil {
+			metrics.AuthFailures.WithLabelValues(failureReason(err)).Inc()
 		}
-
-		tokenString := strings.Split(authHeader, " ")[1]
-		if tokenString == "" {
-			c.JSON(http.StatusForbidden, gin.H{"message": "Authentication failed!"})
-			c.Abort()
+		if errors.Is(err, errAPITokenRejected) {
+			apierror.Abort(c, errAPITokenForbidden)
 			return
 		}
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("TOKEN_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusForbidden, gin.H{"message": "Authentication failed!"})
-			c.Abort()
+		if errors.Is(err, errNoToken) || errors.Is(err, errInvalidToken) || errors.Is(err, errRevokedToken) {
+			c.Header("WWW-Authenticate", "Bearer")
+			apierror.Abort(c, errUnauthenticated)
 			return
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusForbidden, gin.H{"message": "Authentication failed!"})
-			c.Abort()
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err, "Authentication failed, please try again later."))
 			return
 		}
 
-		userId, _ := claims["userId"].(string)
-		email, _ := claims["email"].(string)
-		firstName, _ := claims["firstName"].(string)
-		lastName, _ := claims["lastName"].(string)
-
-		c.Set("userData", map[string]string{
-			"userId":    userId,
-			"email":     email,
-			"firstName": firstName,
-			"lastName":  lastName,
-		})
+		c.Set("userData", userData)
+		c.Next()
+	}
+}
 
+// OptionalAuth sets userData like CheckAuth when a valid token is sent, and
+// otherwise lets the request through anonymously. It does not accept personal
+// access tokens.
+func OptionalAuth(cfg *config.Config, keys *keyring.Keyring, revocations store.RevocationStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userData, err := authenticate(c, cfg, keys, revocations, nil); err == nil {
+			c.Set("userData", userData)
+		}
 		c.Next()
 	}
 }
+
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, errNoToken):
+		return metrics.AuthMissingToken
+	case errors.Is(err, errInvalidToken), errors.Is(err, errAPITokenRejected):
+		return metrics.AuthInvalidToken
+	case errors.Is(err, errRevokedToken):
+		return metrics.AuthRevokedToken
+	default:
+		return metrics.AuthError
+	}
+}
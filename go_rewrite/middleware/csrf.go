@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"backend/apierror"
+	"backend/config"
+	"backend/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errCSRF = apierror.New(http.StatusForbidden, "csrf_failed", "Missing or invalid CSRF token, please reload and try again.")
+
+// CSRF rejects state-changing requests authenticated by session cookies
+// unless their X-CSRF-Token header matches the CSRF cookie. Another site can
+// make a browser send the cookies but cannot read them to set the header.
+func CSRF(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if !session.FromCookies(c, cfg) {
+			c.Next()
+			return
+		}
+
+		cookie, _ := c.Cookie(session.CSRFCookie)
+		header := c.GetHeader(session.CSRFHeader)
+		if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) != 1 {
+			apierror.Abort(c, errCSRF)
+			return
+		}
+
+		c.Next()
+	}
+}
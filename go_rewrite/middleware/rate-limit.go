@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend/apierror"
+	"backend/config"
+	"backend/logging"
+	"backend/metrics"
+	"backend/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errRateLimited = apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "Too many requests, please try again later.")
+
+// ClientIP keys a rate limit by the client's address, as seen through the
+// configured trusted proxies.
+func ClientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// UserID keys a rate limit by the authenticated user, so it must run after
+// CheckAuth.
+func UserID(c *gin.Context) string {
+	return c.MustGet("userData").(map[string]string)["userId"]
+}
+
+// RateLimit rejects requests with 429 once the client that key identifies has
+// used up limit, which is named name in keys and metrics. The limiter's
+// store failing lets requests through, since refusing every request would
+// be worse than briefly not limiting them.
+func RateLimit(cfg *config.Config, limiter *ratelimit.Limiter, name string, limit ratelimit.Limit, key func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !limit.Enabled() || c.Request.Method == "OPTIONS" {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := requestContext(c, cfg)
+		result, err := limiter.Allow(ctx, name+":"+key(c), limit)
+		cancel()
+		if err != nil {
+			logging.FromContext(c).Error("checking rate limit failed", "limit", name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			metrics.RateLimited.WithLabelValues(name).Inc()
+			c.Header("Retry-After", ratelimit.RetryAfter(result.RetryAfter))
+			apierror.Abort(c, errRateLimited)
+			return
+		}
+
+		c.Next()
+	}
+}
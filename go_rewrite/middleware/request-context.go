@@ -0,0 +1,14 @@
+package middleware
+
+import (
+	"context"
+
+	"backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestContext bounds the request's context by the route's deadline.
+func requestContext(c *gin.Context, cfg *config.Config) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.Request.Context(), cfg.RouteTimeout(c.Request.Method, c.FullPath()))
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"backend/apierror"
+	"backend/models"
+	"backend/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errForbidden = apierror.New(http.StatusForbidden, apierror.CodeForbidden, "You are not allowed to do this.")
+
+// RequireRole must run after CheckAuth and only lets the listed roles through.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == "OPTIONS" {
+			c.Next()
+			return
+		}
+
+		userData := c.MustGet("userData").(map[string]string)
+		if !slices.Contains(roles, models.EffectiveRole(userData["role"])) {
+			apierror.Abort(c, errForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission must run after CheckAuth and rejects actors who may not
+// perform action at all. Ownership-dependent checks stay in the handlers.
+func RequirePermission(action policy.Action) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == "OPTIONS" {
+			c.Next()
+			return
+		}
+
+		actor := policy.ActorFromUserData(c.MustGet("userData").(map[string]string))
+		if !policy.Can(actor, action, actor.ID) {
+			apierror.Abort(c, errForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInsufficientScope = apierror.New(http.StatusForbidden, "insufficient_scope", "The API token does not have the scope this needs.")
+
+// RequireScope must run after CheckAuth and rejects personal access tokens
+// that were not granted scope. Sessions are not limited by scopes.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == "OPTIONS" {
+			c.Next()
+			return
+		}
+
+		userData := c.MustGet("userData").(map[string]string)
+		if userData["apiTokenId"] != "" && !slices.Contains(strings.Fields(userData["scopes"]), scope) {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+			apierror.Abort(c, errInsufficientScope)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"backend/apierror"
+	"backend/config"
+	"backend/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errEmailNotVerified = apierror.New(http.StatusForbidden, "email_not_verified", "Please verify your email address first.")
+
+// RequireVerifiedEmail must run after CheckAuth. Unless cfg enables
+// RequireEmailVerification it lets every request through, so the policy can be
+// switched per deployment.
+func RequireVerifiedEmail(cfg *config.Config, users store.UserStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.RequireEmailVerification || c.Request.Method == "OPTIONS" {
+			c.Next()
+			return
+		}
+
+		userData := c.MustGet("userData").(map[string]string)
+		uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+
+		ctx, cancel := requestContext(c, cfg)
+		defer cancel()
+
+		user, err := users.FindByID(ctx, uid)
+		if errors.Is(err, store.ErrNotFound) {
+			apierror.Abort(c, errUnauthenticated)
+			return
+		}
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err, "Authentication failed, please try again later."))
+			return
+		}
+
+		if !user.EmailVerified {
+			apierror.Abort(c, errEmailNotVerified)
+			return
+		}
+
+		c.Next()
+	}
+}
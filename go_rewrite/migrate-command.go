@@ -0,0 +1,85 @@
+package main
+
+import (
+	"backend/migrate"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "usage: backend migrate up [--to VERSION] | down [--steps N] | status"
+
+// runMigrate implements `backend migrate up|down|status`.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		log.Print(migrateUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	to := flags.Int("to", 0, "apply migrations up to this version (default: all)")
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+	timeout := flags.Duration("timeout", 10*time.Minute, "give up after this long")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		log.Print(err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	defer db.Client().Disconnect(ctx)
+
+	migrator := migrate.New(db)
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx, *to)
+		for _, m := range applied {
+			fmt.Printf("applied %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("already up to date")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		w.Flush()
+	default:
+		log.Print(migrateUsage)
+		return 2
+	}
+	return 0
+}
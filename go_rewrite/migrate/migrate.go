@@ -0,0 +1,210 @@
+// Package migrate applies the versioned schema changes in migrations.go and
+// records them in the migrations collection.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is one schema change. Versions are applied in ascending order
+// and must never be reused once released.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// Status describes a migration and whether it has been applied.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// ErrLocked is returned while another process is migrating the database.
+var ErrLocked = errors.New("migrate: another migration is in progress")
+
+const (
+	collection = "migrations"
+	lockID     = "lock"
+	// lockTTL lets a crashed run's lock be taken over.
+	lockTTL = 15 * time.Minute
+)
+
+type record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+}
+
+// New returns a Migrator for the migrations registered in this package.
+func New(db *mongo.Database) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]record, error) {
+	cursor, err := m.db.Collection(collection).Find(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// Status lists every known migration in order. Versions recorded in the
+// database but unknown to this build are listed too, so a rollback to an
+// older binary is noticed.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		status := Status{Version: mig.Version, Name: mig.Name}
+		if r, ok := applied[mig.Version]; ok {
+			status.AppliedAt = &r.AppliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, r := range applied {
+		statuses = append(statuses, Status{Version: r.Version, Name: r.Name + " (unknown)", AppliedAt: &r.AppliedAt})
+	}
+	slices.SortFunc(statuses, func(a, b Status) int { return a.Version - b.Version })
+	return statuses, nil
+}
+
+// Up applies the pending migrations up to and including target, or all of
+// them when target is 0, and returns the ones it applied.
+func (m *Migrator) Up(ctx context.Context, target int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if target > 0 && mig.Version > target {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+
+			if err := mig.Up(ctx, m.db); err != nil {
+				return fmt.Errorf("migration %d %s: %w", mig.Version, mig.Name, err)
+			}
+			_, err := m.db.Collection(collection).InsertOne(ctx, record{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now().UTC()})
+			if err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+
+			if err := mig.Down(ctx, m.db); err != nil {
+				return fmt.Errorf("reverting migration %d %s: %w", mig.Version, mig.Name, err)
+			}
+			_, err := m.db.Collection(collection).DeleteOne(ctx, bson.M{"_id": mig.Version})
+			if err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// locked runs fn while holding the migrations lock, so that two instances
+// starting together do not apply the same migration twice.
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	col := m.db.Collection(collection)
+	now := time.Now().UTC()
+
+	_, err := col.InsertOne(ctx, bson.M{"_id": lockID, "lockedAt": now})
+	if mongo.IsDuplicateKeyError(err) {
+		result, err := col.UpdateOne(ctx,
+			bson.M{"_id": lockID, "lockedAt": bson.M{"$lt": now.Add(-lockTTL)}},
+			bson.M{"$set": bson.M{"lockedAt": now}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return ErrLocked
+		}
+	} else if err != nil {
+		return err
+	}
+
+	defer col.DeleteOne(context.WithoutCancel(ctx), bson.M{"_id": lockID, "lockedAt": now})
+	return fn()
+}
+
+// createIndexes and dropIndexes are the building blocks of most migrations.
+func createIndexes(ctx context.Context, col *mongo.Collection, models ...mongo.IndexModel) error {
+	_, err := col.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+func dropIndexes(ctx context.Context, col *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		_, err := col.Indexes().DropOne(ctx, name)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func index(name string, keys bson.D) mongo.IndexModel {
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name)}
+}
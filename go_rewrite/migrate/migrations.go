@@ -0,0 +1,233 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations must stay sorted by Version. Index names follow MongoDB's
+// defaults, so databases indexed before migrations existed upgrade cleanly.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "unique-user-email",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			err := createIndexes(ctx, db.Collection("users"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "email", Value: 1}},
+				Options: options.Index().SetName("email_1").SetUnique(true),
+			})
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("users share an email address, run `backend doctor` to find them: %w", err)
+			}
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("users"), "email_1")
+		},
+	},
+	{
+		Version: 2,
+		Name:    "reference-indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			err := createIndexes(ctx, db.Collection("blogs"), index("author_1", bson.D{{Key: "author", Value: 1}}))
+			if err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("comments"), index("blog_1", bson.D{{Key: "blog", Value: 1}}))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("blogs"), "author_1"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("comments"), "blog_1")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "blog-tag-indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("blogs"),
+				index("tags_1", bson.D{{Key: "tags", Value: 1}}),
+				index("status_1_tags_1", bson.D{{Key: "status", Value: 1}, {Key: "tags", Value: 1}}),
+			)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("blogs"), "tags_1", "status_1_tags_1")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "text-search-indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// The weights match the search package's field weights.
+			err := createIndexes(ctx, db.Collection("blogs"), mongo.IndexModel{
+				Keys: bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}, {Key: "article", Value: "text"}},
+				Options: options.Index().
+					SetName("blogs_text").
+					SetWeights(bson.D{{Key: "title", Value: 10}, {Key: "description", Value: 5}, {Key: "article", Value: 1}}),
+			})
+			if err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("comments"), index("comments_text", bson.D{{Key: "content", Value: "text"}}))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("blogs"), "blogs_text"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("comments"), "comments_text")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "comment-parent-index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("comments"), index("parentId_1", bson.D{{Key: "parentId", Value: 1}}))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("comments"), "parentId_1")
+		},
+	},
+	{
+		Version: 6,
+		Name:    "rate-limit-collections",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Expire rate limit buckets and login failure runs at expiresAt.
+			expiring := mongo.IndexModel{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetName("expiresAt_1").SetExpireAfterSeconds(0),
+			}
+			if err := createIndexes(ctx, db.Collection("rateLimits"), expiring); err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("loginFailures"), expiring)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("rateLimits"), "expiresAt_1"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("loginFailures"), "expiresAt_1")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "signing-keys",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Instances rotating at once race to insert the next generation.
+			return createIndexes(ctx, db.Collection("signingKeys"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "generation", Value: 1}},
+				Options: options.Index().SetName("generation_1").SetUnique(true),
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("signingKeys"), "generation_1")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "api-tokens",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("apiTokens"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+					Options: options.Index().SetName("tokenHash_1").SetUnique(true),
+				},
+				index("user_1", bson.D{{Key: "user", Value: 1}}),
+				// Tokens without an expiry are left alone.
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+					Options: options.Index().SetName("expiresAt_1").SetExpireAfterSeconds(0),
+				},
+			)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("apiTokens"), "tokenHash_1", "user_1", "expiresAt_1")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "token-indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Tokens and denylist entries are useless once expired, so
+			// MongoDB drops them at expiresAt.
+			expiring := mongo.IndexModel{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetName("expiresAt_1").SetExpireAfterSeconds(0),
+			}
+			err := createIndexes(ctx, db.Collection("refreshTokens"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+					Options: options.Index().SetName("tokenHash_1").SetUnique(true),
+				},
+				index("family_1", bson.D{{Key: "family", Value: 1}}),
+				index("user_1", bson.D{{Key: "user", Value: 1}}),
+				expiring,
+			)
+			if err != nil {
+				return err
+			}
+			err = createIndexes(ctx, db.Collection("oneTimeTokens"),
+				index("purpose_1_tokenHash_1", bson.D{{Key: "purpose", Value: 1}, {Key: "tokenHash", Value: 1}}),
+				index("user_1_purpose_1_createdAt_-1", bson.D{{Key: "user", Value: 1}, {Key: "purpose", Value: 1}, {Key: "createdAt", Value: -1}}),
+				expiring,
+			)
+			if err != nil {
+				return err
+			}
+			// Lookups are by _id, which is always indexed.
+			return createIndexes(ctx, db.Collection("revokedTokens"), expiring)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			err := dropIndexes(ctx, db.Collection("refreshTokens"), "tokenHash_1", "family_1", "user_1", "expiresAt_1")
+			if err != nil {
+				return err
+			}
+			err = dropIndexes(ctx, db.Collection("oneTimeTokens"), "purpose_1_tokenHash_1", "user_1_purpose_1_createdAt_-1", "expiresAt_1")
+			if err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("revokedTokens"), "expiresAt_1")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "unique-blog-slug",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Blogs from before slugs existed have none until they are edited.
+			err := createIndexes(ctx, db.Collection("blogs"), mongo.IndexModel{
+				Keys: bson.D{{Key: "slug", Value: 1}},
+				Options: options.Index().
+					SetName("slug_1").
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"slug": bson.M{"$type": "string"}}),
+			})
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("blogs share a slug, retitle all but one of them: %w", err)
+			}
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("blogs"), "slug_1")
+		},
+	},
+	{
+		Version: 11,
+		Name:    "comment-thread-index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Serves the pages of a blog's top-level comments, oldest first.
+			return createIndexes(ctx, db.Collection("comments"), index("blog_1_parentId_1_date_1__id_1", bson.D{
+				{Key: "blog", Value: 1},
+				{Key: "parentId", Value: 1},
+				{Key: "date", Value: 1},
+				{Key: "_id", Value: 1},
+			}))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("comments"), "blog_1_parentId_1_date_1__id_1")
+		},
+	},
+}
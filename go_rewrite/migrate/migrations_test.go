@@ -0,0 +1,38 @@
+package migrate
+
+import "testing"
+
+func TestMigrations(t *testing.T) {
+	names := make(map[string]bool)
+	for i, mig := range migrations {
+		if mig.Version <= 0 {
+			t.Errorf("migration %q has version %d, want a positive one", mig.Name, mig.Version)
+		}
+		if i > 0 && mig.Version <= migrations[i-1].Version {
+			t.Errorf("version %d follows %d; versions must be unique and ascending", mig.Version, migrations[i-1].Version)
+		}
+		if mig.Name == "" || names[mig.Name] {
+			t.Errorf("version %d has a blank or duplicate name %q", mig.Version, mig.Name)
+		}
+		names[mig.Name] = true
+		if mig.Up == nil || mig.Down == nil {
+			t.Errorf("version %d %s must have both Up and Down", mig.Version, mig.Name)
+		}
+	}
+
+	// Released versions must keep their numbers.
+	for version, name := range map[int]string{9: "token-indexes", 10: "unique-blog-slug"} {
+		found := false
+		for _, mig := range migrations {
+			if mig.Version == version {
+				found = true
+				if mig.Name != name {
+					t.Errorf("version %d is %s, want %s", version, mig.Name, name)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("version %d %s is missing", version, name)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scopes a personal access token can be granted. Sessions are not limited by
+// scopes; a token can only do what its owner's role allows on top of them.
+const (
+	ScopeBlogsRead     = "blogs:read"
+	ScopeBlogsWrite    = "blogs:write"
+	ScopeCommentsWrite = "comments:write"
+)
+
+// APITokenPrefix starts every personal access token, so CheckAuth can tell
+// them from JWTs and secret scanners can spot leaked ones.
+const APITokenPrefix = "pat_"
+
+// APIToken is a long-lived personal access token for scripts. Only the hash
+// of the token is stored; Hint keeps its first characters so users can tell
+// their tokens apart.
+type APIToken struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	User       primitive.ObjectID `json:"-" bson:"user"`
+	Name       string             `json:"name" bson:"name"`
+	Scopes     []string           `json:"scopes" bson:"scopes"`
+	TokenHash  string             `json:"-" bson:"tokenHash"`
+	Hint       string             `json:"hint" bson:"hint"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	ExpiresAt  *time.Time         `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	LastUsedAt *time.Time         `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+}
+
+// HashAPIToken is how personal access tokens are looked up and persisted.
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type CreateAPITokenRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=blogs:read blogs:write comments:write"`
+	// ExpiresAt is optional; tokens without it last until revoked.
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// CreateAPITokenResponse is the only time the token itself is shown.
+type CreateAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+type APITokenListResponse struct {
+	Tokens []APIToken `json:"tokens"`
+}
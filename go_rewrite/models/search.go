@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Snippet is an excerpt of a matching field. Text is HTML-escaped with the
+// matched words wrapped in <mark> tags.
+type Snippet struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+type SearchResult struct {
+	ID          string     `json:"_id"`
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug,omitempty"`
+	Author      Author     `json:"author"`
+	Description string     `json:"description"`
+	Tags        []string   `json:"tags"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
+	Score       float64    `json:"score"`
+	Snippets    []Snippet  `json:"snippets"`
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is a single-use credential exchanged at /user/refresh. Every
+// rotation keeps the Family of the login that started the chain, so reuse of a
+// consumed token can revoke the whole chain at once.
+type RefreshToken struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	User      primitive.ObjectID `json:"user" bson:"user"`
+	Family    primitive.ObjectID `json:"family" bson:"family"`
+	TokenHash string             `json:"-" bson:"tokenHash"`
+	ExpiresAt time.Time          `json:"expiresAt" bson:"expiresAt"`
+	Used      bool               `json:"used" bson:"used"`
+	Revoked   bool               `json:"revoked" bson:"revoked"`
+}
+
+// RevokedToken is a denylist entry for an access token's jti. It only needs to
+// live until the access token would have expired on its own. Entries that set
+// IssuedBefore revoke every token of User issued at or before that instant
+// instead.
+type RevokedToken struct {
+	JTI          string             `json:"jti" bson:"_id"`
+	User         primitive.ObjectID `json:"user,omitempty" bson:"user,omitempty"`
+	IssuedBefore time.Time          `json:"issuedBefore,omitempty" bson:"issuedBefore,omitempty"`
+	ExpiresAt    time.Time          `json:"expiresAt" bson:"expiresAt"`
+}
+
+// One-time token purposes.
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeVerifyEmail   = "verify_email"
+)
+
+// OneTimeToken is a hashed, expiring token that is mailed to a user and can be
+// redeemed exactly once for the action named by Purpose.
+type OneTimeToken struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	User      primitive.ObjectID `json:"user" bson:"user"`
+	Purpose   string             `json:"purpose" bson:"purpose"`
+	TokenHash string             `json:"-" bson:"tokenHash"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	ExpiresAt time.Time          `json:"expiresAt" bson:"expiresAt"`
+	Used      bool               `json:"used" bson:"used"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
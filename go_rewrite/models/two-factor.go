@@ -0,0 +1,47 @@
+package models
+
+// TwoFactor is a user's TOTP authenticator. Setup stores Secret, and it is
+// Enabled once a code from the authenticator has confirmed it.
+type TwoFactor struct {
+	Secret  string `bson:"secret,omitempty"`
+	Enabled bool   `bson:"enabled"`
+	// RecoveryCodes holds the hashes of the unused recovery codes.
+	RecoveryCodes []string `bson:"recoveryCodes,omitempty"`
+	// LastStep is the time step of the last accepted code; codes of that or
+	// an earlier step are rejected, so none can be replayed.
+	LastStep int64 `bson:"lastStep,omitempty"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	// URI is the otpauth URI to show as a QR code.
+	URI string `json:"otpauthUri"`
+}
+
+// TwoFactorChallenge is Login's response when the password was right but a
+// second factor is still needed.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+	ExpiresIn         int    `json:"expiresIn"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// TwoFactorCodeRequest carries a code from the authenticator or, where noted,
+// a recovery code.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
@@ -13,14 +13,43 @@ type User struct {
 	Email     string               `json:"email" bson:"email" binding:"required,email"`
 	Password  string               `json:"password" bson:"password" binding:"required,min=8"`
 	Blogs     []primitive.ObjectID `json:"blogs" bson:"blogs"`
+
+	EmailVerified bool   `json:"emailVerified" bson:"emailVerified"`
+	Role          string `json:"role" bson:"role"`
+
+	TwoFactor TwoFactor `json:"-" bson:"twoFactor"`
+}
+
+// Roles, from least to most privileged.
+const (
+	RoleReader = "reader"
+	RoleAuthor = "author"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// EffectiveRole maps accounts created before roles existed to RoleAuthor,
+// which is what every user could do at the time.
+func EffectiveRole(role string) string {
+	if role == "" {
+		return RoleAuthor
+	}
+	return role
 }
 
 type UserResponse struct {
-	ID        string `json:"user"`
-	Email     string `json:"email"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Token     string `json:"token,omitempty"`
+	ID           string `json:"user"`
+	Email        string `json:"email"`
+	FirstName    string `json:"firstName"`
+	LastName     string `json:"lastName"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// CSRFToken replaces the tokens when they are set as session cookies.
+	CSRFToken string `json:"csrfToken,omitempty"`
+
+	EmailVerified    bool   `json:"emailVerified"`
+	Role             string `json:"role"`
+	TwoFactorEnabled bool   `json:"twoFactorEnabled"`
 }
 
 type LoginRequest struct {
@@ -35,31 +64,86 @@ type SignupRequest struct {
 	Password  string `json:"password" binding:"required,min=8"`
 }
 
+type RoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=reader author editor admin"`
+}
+
 type Comment struct {
 	ID      primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	User    primitive.ObjectID `json:"user" bson:"user" binding:"required"`
 	Content string             `json:"content" bson:"content" binding:"required"`
 	Date    time.Time          `json:"date" bson:"date" binding:"required"`
 	Blog    primitive.ObjectID `json:"blog" bson:"blog" binding:"required"`
+
+	// ParentID is zero for top-level comments; Depth counts the ancestors.
+	ParentID primitive.ObjectID `json:"parentId,omitempty" bson:"parentId,omitempty"`
+	Depth    int                `json:"depth" bson:"depth"`
+	// Deleted comments keep their place in a thread that still has replies.
+	Deleted bool `json:"deleted,omitempty" bson:"deleted,omitempty"`
 }
 
 type Blog struct {
 	ID          primitive.ObjectID   `json:"_id,omitempty" bson:"_id,omitempty"`
 	Title       string               `json:"title" bson:"title" binding:"required"`
+	Slug        string               `json:"slug" bson:"slug"`
+	SlugHistory []string             `json:"-" bson:"slugHistory,omitempty"`
 	Author      primitive.ObjectID   `json:"author" bson:"author" binding:"required"`
 	Description string               `json:"description" bson:"description" binding:"required"`
 	Article     string               `json:"article" bson:"article" binding:"required,min=500"`
 	Comments    []primitive.ObjectID `json:"comments" bson:"comments"`
+	Tags        []string             `json:"tags" bson:"tags"`
+
+	Status      string     `json:"status" bson:"status"`
+	PublishAt   *time.Time `json:"publishAt,omitempty" bson:"publishAt,omitempty"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty" bson:"publishedAt,omitempty"`
+}
+
+// Blog statuses. Only published blogs are listed publicly.
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// CurrentStatus treats blogs stored before statuses existed as published.
+func (b *Blog) CurrentStatus() string {
+	if b.Status == "" {
+		return StatusPublished
+	}
+	return b.Status
+}
+
+func (b *Blog) IsPublished() bool {
+	return b.CurrentStatus() == StatusPublished
 }
 
 type BlogRequest struct {
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description" binding:"required"`
 	Article     string `json:"article" binding:"required,min=500"`
+	// Tags replaces the blog's tags when present; omit it to keep them.
+	Tags []string `json:"tags" binding:"omitempty,max=10,dive,max=40"`
+
+	// Status and PublishAt are only honoured by CreateBlog; later changes go
+	// through the publish, unpublish and archive endpoints.
+	Status    string     `json:"status" binding:"omitempty,oneof=draft scheduled published"`
+	PublishAt *time.Time `json:"publishAt"`
+}
+
+type PublishRequest struct {
+	PublishAt *time.Time `json:"publishAt"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
 }
 
 type CommentRequest struct {
 	Comment string `json:"comment" binding:"required"`
+	// ParentID makes the comment a reply; it is ignored on updates.
+	ParentID string `json:"parentId"`
 }
 
 type Author struct {
@@ -71,23 +155,53 @@ type Author struct {
 type BlogResponse struct {
 	ID          string            `json:"_id,omitempty" bson:"_id,omitempty"`
 	Title       string            `json:"title"`
+	Slug        string            `json:"slug,omitempty"`
 	Author      Author            `json:"author"`
 	Description string            `json:"description"`
 	Article     string            `json:"article"`
 	Comments    []CommentResponse `json:"comments"`
+	// ThreadCount is the number of top-level comments, which Comments pages through.
+	ThreadCount int        `json:"threadCount,omitempty"`
+	Tags        []string   `json:"tags"`
+	Status      string     `json:"status"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
 }
 
 type CommentResponse struct {
-	ID      string    `json:"_id,omitempty" bson:"_id,omitempty"`
-	User    Author    `json:"user"`
-	Content string    `json:"content"`
-	Date    time.Time `json:"date"`
-	Blog    string    `json:"blog,omitempty"`
+	ID       string    `json:"_id,omitempty" bson:"_id,omitempty"`
+	User     Author    `json:"user"`
+	Content  string    `json:"content"`
+	Date     time.Time `json:"date"`
+	Blog     string    `json:"blog,omitempty"`
+	ParentID string    `json:"parentId,omitempty"`
+	Depth    int       `json:"depth"`
+	Deleted  bool      `json:"deleted,omitempty"`
+
+	// Replies holds the first page of direct replies in tree responses;
+	// ReplyCount is the total, so clients know when to fetch more.
+	Replies    []CommentResponse `json:"replies,omitempty"`
+	ReplyCount int               `json:"replyCount"`
+}
+
+type CommentThreadResponse struct {
+	Comments []CommentResponse `json:"comments"`
+	Total    int               `json:"total"`
 }
 
 type UserBlogResponse struct {
-	Blogs  []Blog `json:"blogs"`
-	Author Author `json:"author"`
+	Blogs      []Blog `json:"blogs"`
+	Author     Author `json:"author"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+type BlogListResponse struct {
+	Tag        string         `json:"tag,omitempty"`
+	Blogs      []BlogResponse `json:"blogs"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+	PrevCursor string         `json:"prevCursor,omitempty"`
+	Total      *int64         `json:"total,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Action string
+
+const (
+	CreateBlog    Action = "blog:create"
+	UpdateBlog    Action = "blog:update"
+	DeleteBlog    Action = "blog:delete"
+	ReadDraft     Action = "blog:read-unpublished"
+	CreateComment Action = "comment:create"
+	UpdateComment Action = "comment:update"
+	DeleteComment Action = "comment:delete"
+)
+
+// Actor is the authenticated user performing an action.
+type Actor struct {
+	ID   primitive.ObjectID
+	Role string
+}
+
+// Anonymous is the actor for requests without credentials. It owns nothing and
+// has no role, so Can denies it every action.
+var Anonymous = Actor{}
+
+// ActorFromUserData builds an Actor from the map CheckAuth stores under "userData".
+func ActorFromUserData(userData map[string]string) Actor {
+	uid, _ := primitive.ObjectIDFromHex(userData["userId"])
+	return Actor{ID: uid, Role: models.EffectiveRole(userData["role"])}
+}
+
+// Can is the single place authorization decisions are made. owner is the
+// author of the blog or comment being acted on, or the zero ObjectID for
+// actions that do not target an existing document.
+func Can(actor Actor, action Action, owner primitive.ObjectID) bool {
+	isOwner := !owner.IsZero() && owner == actor.ID
+
+	switch actor.Role {
+	case models.RoleAdmin:
+		return true
+	case models.RoleEditor:
+		switch action {
+		case CreateBlog, CreateComment, UpdateComment, DeleteComment:
+			return true
+		case UpdateBlog, DeleteBlog, ReadDraft:
+			return isOwner
+		}
+	case models.RoleAuthor:
+		switch action {
+		case CreateBlog, CreateComment:
+			return true
+		case UpdateBlog, DeleteBlog, ReadDraft, UpdateComment, DeleteComment:
+			return isOwner
+		}
+	case models.RoleReader:
+		switch action {
+		case CreateComment:
+			return true
+		case ReadDraft, UpdateComment, DeleteComment:
+			return isOwner
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how often the memory store drops buckets that have refilled
+// and failure runs that have expired.
+const sweepEvery = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled and can be dropped.
+	full time.Time
+}
+
+type failureRun struct {
+	Failures
+	expires time.Time
+}
+
+// MemoryStore keeps counters in the process, so each instance of a
+// multi-instance deployment enforces its limits separately.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	failures  map[string]*failureRun
+	nextSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, failures: map[string]*failureRun{}}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = min(capacity, b.tokens+now.Sub(b.updated).Seconds()*limit.rate())
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((capacity - b.tokens) / limit.rate() * float64(time.Second)))
+	return allowed, b.tokens, nil
+}
+
+func (s *MemoryStore) AddFailure(ctx context.Context, key string, now time.Time, keepFor time.Duration) (Failures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	run, ok := s.failures[key]
+	if !ok || !now.Before(run.expires) {
+		run = &failureRun{}
+		s.failures[key] = run
+	}
+	run.Count++
+	run.Last = now
+	run.expires = now.Add(keepFor)
+	return run.Failures, nil
+}
+
+func (s *MemoryStore) Failures(ctx context.Context, key string, now time.Time) (Failures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.failures[key]
+	if !ok || !now.Before(run.expires) {
+		return Failures{}, nil
+	}
+	return run.Failures, nil
+}
+
+func (s *MemoryStore) ResetFailures(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+// sweep must be called with mu held.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(sweepEvery)
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, run := range s.failures {
+		if !now.Before(run.expires) {
+			delete(s.failures, key)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps counters in the rateLimits and loginFailures collections,
+// so that every instance sharing the database shares the limits. The
+// rate-limit-collections migration expires their documents.
+type MongoStore struct {
+	buckets  *mongo.Collection
+	failures *mongo.Collection
+}
+
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{buckets: db.Collection("rateLimits"), failures: db.Collection("loginFailures")}
+}
+
+type bucketDoc struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+type failureDoc struct {
+	Count int       `bson:"count"`
+	Last  time.Time `bson:"last"`
+}
+
+// Take refills and takes from the bucket in a single update pipeline, so
+// that concurrent requests cannot spend the same token.
+func (s *MongoStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, float64, error) {
+	capacity := float64(limit.Requests)
+	elapsed := bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated", now}}}}, 1000}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{capacity, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", capacity}},
+				bson.M{"$multiply": bson.A{elapsed, limit.rate()}},
+			}}}},
+			"updated": now,
+		}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			// An idle bucket refills within Per, after which it can go.
+			"expiresAt": now.Add(limit.Per),
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var doc bucketDoc
+	err := s.buckets.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&doc)
+	// Two first requests can race to insert the bucket; the loser retries
+	// against the winner's document.
+	if mongo.IsDuplicateKeyError(err) {
+		err = s.buckets.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&doc)
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	return doc.Allowed, doc.Tokens, nil
+}
+
+func (s *MongoStore) AddFailure(ctx context.Context, key string, now time.Time, keepFor time.Duration) (Failures, error) {
+	// A run that has expired but not yet been removed starts over.
+	live := bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$expiresAt", now}}, now}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"count":     bson.M{"$cond": bson.A{live, bson.M{"$add": bson.A{"$count", 1}}, 1}},
+			"last":      now,
+			"expiresAt": now.Add(keepFor),
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var doc failureDoc
+	err := s.failures.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&doc)
+	if mongo.IsDuplicateKeyError(err) {
+		err = s.failures.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&doc)
+	}
+	if err != nil {
+		return Failures{}, err
+	}
+	return Failures{Count: doc.Count, Last: doc.Last}, nil
+}
+
+func (s *MongoStore) Failures(ctx context.Context, key string, now time.Time) (Failures, error) {
+	// MongoDB removes expired documents only periodically.
+	var doc failureDoc
+	err := s.failures.FindOne(ctx, bson.M{"_id": key, "expiresAt": bson.M{"$gt": now}}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Failures{}, nil
+	}
+	if err != nil {
+		return Failures{}, err
+	}
+	return Failures{Count: doc.Count, Last: doc.Last}, nil
+}
+
+func (s *MongoStore) ResetFailures(ctx context.Context, key string) error {
+	_, err := s.failures.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
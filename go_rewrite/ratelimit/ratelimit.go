@@ -0,0 +1,187 @@
+// Package ratelimit throttles clients with token buckets and locks accounts
+// out after repeated failed logins. The counters live in a Store, so that
+// instances sharing a MongoStore also share their limits.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests per Per on average, in bursts of up to Requests. The
+// zero Limit allows everything.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// rate is the number of tokens the bucket regains per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// ParseLimit reads a limit written as "requests/duration", such as "10/1m" or
+// "100/h". An empty string or "off" disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q is not requests/duration, such as 10/1m", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("%q: requests must be a positive whole number", s)
+	}
+	per = strings.TrimSpace(per)
+	if per != "" && strings.IndexAny(per[:1], "0123456789.") < 0 {
+		per = "1" + per
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%q: %q is not a positive duration", s, per)
+	}
+	return Limit{Requests: n, Per: d}, nil
+}
+
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// UnmarshalText lets configuration files and flags hold limits.
+func (l *Limit) UnmarshalText(text []byte) error {
+	parsed, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// Lockout locks an account for Base once it has Threshold consecutive failed
+// logins, doubling the lock with every further failure up to Max. A zero
+// Threshold disables lockouts.
+type Lockout struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+// Duration returns how long failures consecutive failures lock an account.
+func (l Lockout) Duration(failures int) time.Duration {
+	if l.Threshold <= 0 || failures < l.Threshold {
+		return 0
+	}
+	d := l.Base
+	for i := l.Threshold; i < failures && d < l.Max; i++ {
+		d *= 2
+	}
+	return min(d, l.Max)
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next request would be allowed.
+	RetryAfter time.Duration
+}
+
+// Failures is an account's run of failed logins.
+type Failures struct {
+	Count int
+	Last  time.Time
+}
+
+// Store keeps buckets and failure counts by key. Every method must be safe
+// for concurrent use, across instances for shared stores.
+type Store interface {
+	// Take removes a token from key's bucket if one is left and returns the
+	// tokens remaining. Buckets start full and may be dropped once full again.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (allowed bool, tokens float64, err error)
+	// AddFailure records a failure for key at now and returns the new run.
+	// The run may be forgotten after keepFor.
+	AddFailure(ctx context.Context, key string, now time.Time, keepFor time.Duration) (Failures, error)
+	// Failures returns key's run as of now, which is empty when there is none.
+	Failures(ctx context.Context, key string, now time.Time) (Failures, error)
+	ResetFailures(ctx context.Context, key string) error
+}
+
+// Limiter applies limits and lockouts on top of a Store.
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+func New(store Store) *Limiter {
+	return &Limiter{store: store, now: time.Now}
+}
+
+// Allow takes a request from key's bucket for limit.
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if !limit.Enabled() {
+		return Result{Allowed: true, Remaining: math.MaxInt}, nil
+	}
+
+	allowed, tokens, err := l.store.Take(ctx, key, limit, l.now())
+	if err != nil {
+		return Result{Allowed: true}, err
+	}
+	result := Result{Allowed: allowed, Remaining: int(tokens)}
+	if tokens < 1 {
+		result.RetryAfter = time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+	}
+	return result, nil
+}
+
+// LockedFor returns how much longer key is locked out, or 0.
+func (l *Limiter) LockedFor(ctx context.Context, key string, lockout Lockout) (time.Duration, error) {
+	if lockout.Threshold <= 0 {
+		return 0, nil
+	}
+	now := l.now()
+	failures, err := l.store.Failures(ctx, key, now)
+	if err != nil {
+		return 0, err
+	}
+	return max(failures.Last.Add(lockout.Duration(failures.Count)).Sub(now), 0), nil
+}
+
+// Fail records a failed login for key and returns the lock it now incurs.
+// The run is forgotten once no failure has been recorded for twice Max.
+func (l *Limiter) Fail(ctx context.Context, key string, lockout Lockout) (time.Duration, error) {
+	if lockout.Threshold <= 0 {
+		return 0, nil
+	}
+	failures, err := l.store.AddFailure(ctx, key, l.now(), 2*lockout.Max)
+	if err != nil {
+		return 0, err
+	}
+	return lockout.Duration(failures.Count), nil
+}
+
+// Succeed ends key's run of failures.
+func (l *Limiter) Succeed(ctx context.Context, key string) error {
+	return l.store.ResetFailures(ctx, key)
+}
+
+// RetryAfter renders d as the whole seconds a Retry-After header expects,
+// rounding up so that clients do not retry too early.
+func RetryAfter(d time.Duration) string {
+	return strconv.Itoa(max(int(math.Ceil(d.Seconds())), 1))
+}
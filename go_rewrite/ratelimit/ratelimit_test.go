@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// clock is a settable time source for Limiter.now.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestLimiter() (*Limiter, *clock) {
+	c := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return &Limiter{store: NewMemoryStore(), now: c.Now}, c
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Limit
+		err  bool
+	}{
+		{"", Limit{}, false},
+		{"off", Limit{}, false},
+		{"10/1m", Limit{10, time.Minute}, false},
+		{" 100 / h ", Limit{100, time.Hour}, false},
+		{"5/30s", Limit{5, 30 * time.Second}, false},
+		{"10", Limit{}, true},
+		{"0/1m", Limit{}, true},
+		{"-1/1m", Limit{}, true},
+		{"10/0s", Limit{}, true},
+		{"10/fortnight", Limit{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("ParseLimit(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestLockoutDuration(t *testing.T) {
+	lockout := Lockout{Threshold: 3, Base: time.Minute, Max: 5 * time.Minute}
+	for failures, want := range []time.Duration{0, 0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		if got := lockout.Duration(failures); got != want {
+			t.Errorf("Duration(%d) = %v, want %v", failures, got, want)
+		}
+	}
+	if got := (Lockout{Base: time.Minute, Max: time.Hour}).Duration(100); got != 0 {
+		t.Errorf("disabled lockout = %v, want 0", got)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	ctx := context.Background()
+	l, c := newTestLimiter()
+	limit := Limit{Requests: 3, Per: time.Minute} // a token every 20s
+
+	allow := func(key string, wantAllowed bool, wantRetry time.Duration) {
+		t.Helper()
+		result, err := l.Allow(ctx, key, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != wantAllowed || result.RetryAfter != wantRetry {
+			t.Errorf("Allow(%s) = %v retry after %v, want %v retry after %v", key, result.Allowed, result.RetryAfter, wantAllowed, wantRetry)
+		}
+	}
+
+	// A full bucket allows a burst of Requests.
+	allow("a", true, 0)
+	allow("a", true, 0)
+	allow("a", true, 20*time.Second)
+	allow("a", false, 20*time.Second)
+	allow("b", true, 0)
+
+	// It refills at Requests per Per.
+	c.Advance(10 * time.Second)
+	allow("a", false, 10*time.Second)
+	c.Advance(10 * time.Second)
+	allow("a", true, 20*time.Second)
+
+	// But never beyond Requests.
+	c.Advance(time.Hour)
+	allow("a", true, 0)
+	allow("a", true, 0)
+	allow("a", true, 20*time.Second)
+	allow("a", false, 20*time.Second)
+
+	if result, _ := l.Allow(ctx, "a", Limit{}); !result.Allowed {
+		t.Error("the zero limit refused a request")
+	}
+}
+
+func TestLockout(t *testing.T) {
+	ctx := context.Background()
+	l, c := newTestLimiter()
+	lockout := Lockout{Threshold: 3, Base: time.Minute, Max: 4 * time.Minute}
+
+	fail := func(want time.Duration) {
+		t.Helper()
+		if got, err := l.Fail(ctx, "ada", lockout); err != nil || got != want {
+			t.Errorf("Fail = %v, %v, want %v", got, err, want)
+		}
+	}
+	lockedFor := func(want time.Duration) {
+		t.Helper()
+		if got, err := l.LockedFor(ctx, "ada", lockout); err != nil || got != want {
+			t.Errorf("LockedFor = %v, %v, want %v", got, err, want)
+		}
+	}
+
+	fail(0)
+	fail(0)
+	lockedFor(0)
+	fail(time.Minute)
+	lockedFor(time.Minute)
+
+	// The lock runs out on its own.
+	c.Advance(40 * time.Second)
+	lockedFor(20 * time.Second)
+	c.Advance(20 * time.Second)
+	lockedFor(0)
+
+	// Further failures double it up to Max.
+	fail(2 * time.Minute)
+	fail(4 * time.Minute)
+	fail(4 * time.Minute)
+	lockedFor(4 * time.Minute)
+
+	// A successful login ends the run.
+	if err := l.Succeed(ctx, "ada"); err != nil {
+		t.Fatal(err)
+	}
+	lockedFor(0)
+	fail(0)
+
+	// So does a pause of twice Max.
+	fail(0)
+	c.Advance(2 * lockout.Max)
+	lockedFor(0)
+	fail(0)
+}
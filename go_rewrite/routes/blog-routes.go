@@ -3,18 +3,34 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
+	"backend/policy"
 
 	"github.com/gin-gonic/gin"
 )
 
-func BlogRoutes(router *gin.Engine) {
-	router.GET("/blogs/all", controllers.GetAllBlogs)
-	router.GET("/blogs/blog/:bid", controllers.GetBlogById)
+func BlogRoutes(router *gin.Engine, h *controllers.Handler) {
+	viewer := middleware.OptionalAuth(h.Config, h.Keys, h.Revocations)
+
+	router.GET("/blogs", viewer, h.GetAllBlogs)
+	router.GET("/blogs/all", viewer, h.GetAllBlogs)
+	router.GET("/blogs/blog/:bid", viewer, h.GetBlogById)
+	router.GET("/blogs/:slug", viewer, h.GetBlogBySlug)
+	router.GET("/tags", h.GetTags)
+	router.GET("/tags/:tag", viewer, h.GetTagBlogs)
+	router.GET("/blogs/comment/:cid/replies", viewer, h.GetCommentReplies)
+	router.GET("/search", h.SearchBlogs)
 
 	authorized:=router.Group("")
 
-	authorized.Use(middleware.CheckAuth())
-	authorized.POST("/blogs/comment/:bid", controllers.MakeComment)
-	authorized.PATCH("/blogs/comment/:cid", controllers.UpdateComment)
-	authorized.DELETE("/blogs/comment/:cid", controllers.DeleteComment)
+	// Every route here can be scripted with a personal access token.
+	authorized.Use(middleware.CheckAuth(h.Config, h.Keys, h.Revocations, &middleware.APITokens{Tokens: h.APITokens, Users: h.Users}))
+	verified := middleware.RequireVerifiedEmail(h.Config, h.Users)
+	writeComments := middleware.RequireScope(models.ScopeCommentsWrite)
+	commentIPLimit := middleware.RateLimit(h.Config, h.Limiter, "comment_ip", h.Config.CommentIPLimit, middleware.ClientIP)
+	commentUserLimit := middleware.RateLimit(h.Config, h.Limiter, "comment_user", h.Config.CommentLimit, middleware.UserID)
+
+	authorized.POST("/blogs/comment/:bid", writeComments, commentIPLimit, commentUserLimit, middleware.RequirePermission(policy.CreateComment), verified, h.MakeComment)
+	authorized.PATCH("/blogs/comment/:cid", writeComments, h.UpdateComment)
+	authorized.DELETE("/blogs/comment/:cid", writeComments, h.DeleteComment)
 }
@@ -0,0 +1,119 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/models"
+	"backend/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recordingCommentStore remembers every comment it has returned.
+type recordingCommentStore struct {
+	store.CommentStore
+	mu     sync.Mutex
+	loaded map[primitive.ObjectID]bool
+}
+
+func (s *recordingCommentStore) record(comments []models.Comment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, comment := range comments {
+		s.loaded[comment.ID] = true
+	}
+}
+
+func (s *recordingCommentStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Comment, error) {
+	comments, err := s.CommentStore.FindByIDs(ctx, ids)
+	s.record(comments)
+	return comments, err
+}
+
+func (s *recordingCommentStore) ListReplies(ctx context.Context, bid, parent primitive.ObjectID, offset, limit int) ([]models.Comment, int64, error) {
+	comments, total, err := s.CommentStore.ListReplies(ctx, bid, parent, offset, limit)
+	s.record(comments)
+	return comments, total, err
+}
+
+func (s *recordingCommentStore) FindReplies(ctx context.Context, parents []primitive.ObjectID) ([]models.Comment, error) {
+	comments, err := s.CommentStore.FindReplies(ctx, parents)
+	s.record(comments)
+	return comments, err
+}
+
+func TestCommentThreadPaging(t *testing.T) {
+	ctx := context.Background()
+	stores := store.NewMemoryStores()
+	router := newTestRouter(t, nil, stores)
+
+	call(t, router, http.MethodPost, "/user/signup", "", models.SignupRequest{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Email:     "ada@example.com",
+		Password:  "correct horse battery",
+	}, http.StatusCreated, nil)
+	var user models.UserResponse
+	call(t, router, http.MethodPost, "/user/login", "", models.LoginRequest{
+		Email:    "ada@example.com",
+		Password: "correct horse battery",
+	}, http.StatusOK, &user)
+	bid, _ := primitive.ObjectIDFromHex(createBlog(t, router, user.Token, "Notes"))
+	author, err := stores.Users.FindByEmail(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three threads; the second has a reply with a reply of its own.
+	start := time.Now()
+	comment := func(content string, parent *models.Comment) *models.Comment {
+		c := &models.Comment{User: author.ID, Content: content, Blog: bid, Date: start}
+		start = start.Add(time.Second)
+		if parent != nil {
+			c.ParentID, c.Depth = parent.ID, parent.Depth+1
+		}
+		if err := stores.Comments.Create(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		if err := stores.Blogs.AddComment(ctx, bid, c.ID); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	first := comment("First", nil)
+	second := comment("Second", nil)
+	reply := comment("Reply", second)
+	nested := comment("Nested", reply)
+	third := comment("Third", nil)
+
+	recording := &recordingCommentStore{CommentStore: stores.Comments, loaded: map[primitive.ObjectID]bool{}}
+	stores.Comments = recording
+	router = newTestRouter(t, nil, stores)
+
+	var fetched struct {
+		Blog models.BlogResponse `json:"blog"`
+	}
+	call(t, router, http.MethodGet, "/blogs/blog/"+bid.Hex()+"?threadLimit=1&threadOffset=1", "", nil, http.StatusOK, &fetched)
+
+	blog := fetched.Blog
+	if blog.ThreadCount != 3 || len(blog.Comments) != 1 || blog.Comments[0].ID != second.ID.Hex() {
+		t.Fatalf("got %d of %d threads, want the second of 3", len(blog.Comments), blog.ThreadCount)
+	}
+	got := blog.Comments[0]
+	if got.ReplyCount != 1 || got.Replies[0].ID != reply.ID.Hex() || got.Replies[0].ReplyCount != 1 || got.Replies[0].Replies[0].ID != nested.ID.Hex() {
+		t.Errorf("thread = %+v, want the reply and the nested reply", got)
+	}
+	if recording.loaded[first.ID] || recording.loaded[third.ID] {
+		t.Error("comments outside the requested page were loaded")
+	}
+
+	var replies models.CommentThreadResponse
+	call(t, router, http.MethodGet, "/blogs/comment/"+second.ID.Hex()+"/replies", "", nil, http.StatusOK, &replies)
+	if replies.Total != 1 || len(replies.Comments) != 1 || replies.Comments[0].ID != reply.ID.Hex() || replies.Comments[0].Replies[0].ID != nested.ID.Hex() {
+		t.Errorf("replies = %+v, want the reply with its nested reply", replies)
+	}
+}
@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/mailer"
+	"backend/models"
+	"backend/store"
+)
+
+var errOutage = errors.New("database unreachable")
+
+type downUserStore struct{ store.UserStore }
+
+func (downUserStore) FindByEmail(context.Context, string) (*models.User, error) {
+	return nil, errOutage
+}
+
+type downOneTimeTokenStore struct{ store.OneTimeTokenStore }
+
+func (downOneTimeTokenStore) Create(context.Context, *models.OneTimeToken) error {
+	return errOutage
+}
+
+func TestForgotPasswordRevealsNothing(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		email    string
+		setup    func(*store.Stores)
+		wantMail bool
+	}{
+		{"account", "ada@example.com", nil, true},
+		{"no account", "bob@example.com", nil, false},
+		{"lookup fails", "ada@example.com", func(s *store.Stores) { s.Users = downUserStore{s.Users} }, false},
+		{"token fails", "ada@example.com", func(s *store.Stores) { s.OneTimeTokens = downOneTimeTokenStore{s.OneTimeTokens} }, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stores := store.NewMemoryStores()
+			err := stores.Users.Create(context.Background(), &models.User{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.setup != nil {
+				tc.setup(stores)
+			}
+			h := newTestHandler(t, nil, stores)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/user/password/forgot", strings.NewReader(`{"email":"`+tc.email+`"}`))
+			req.Header.Set("Content-Type", "application/json")
+			NewRouter(h).ServeHTTP(rec, req)
+
+			want := `{"message":"If an account exists for this email, a reset link has been sent."}`
+			if rec.Code != http.StatusOK || rec.Body.String() != want {
+				t.Errorf("response = %d %s, want 200 %s", rec.Code, rec.Body, want)
+			}
+
+			h.Wait()
+			sent := h.Mailer.(*mailer.LogMailer).Sent()
+			if got := len(sent) == 1 && sent[0].To == tc.email; got != tc.wantMail || len(sent) > 1 {
+				t.Errorf("sent %v, want a reset mail = %v", sent, tc.wantMail)
+			}
+		})
+	}
+}
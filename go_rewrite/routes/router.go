@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"backend/apierror"
+	"backend/controllers"
+	"backend/logging"
+	"backend/metrics"
+	"backend/middleware"
+	"backend/session"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// NewRouter builds the complete Gin engine around h. Tests can pass a Handler
+// backed by store.NewMemoryStores to exercise every route without MongoDB.
+func NewRouter(h *controllers.Handler) *gin.Engine {
+	apierror.UseJSONFieldNames()
+
+	router := gin.New()
+	// Validated by config, so this cannot fail.
+	router.SetTrustedProxies(h.Config.TrustedProxies)
+	router.Use(logging.RequestID(), logging.AccessLog(), metrics.Middleware(), apierror.Middleware(), apierror.Recovery())
+
+	router.Use(cors.New(cors.Config{
+		AllowOrigins:     h.Config.CORSOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", logging.RequestIDHeader, session.CSRFHeader},
+		ExposeHeaders:    []string{"Content-Length", logging.RequestIDHeader, "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+	router.Use(middleware.CSRF(h.Config))
+
+	router.GET("/healthz", h.Healthz)
+	router.GET("/readyz", h.Readyz)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.GET("/.well-known/jwks.json", h.JWKS)
+
+	UserRoutes(router, h)
+	BlogRoutes(router, h)
+
+	router.NoRoute(func(c *gin.Context) {
+		apierror.Abort(c, apierror.New(http.StatusNotFound, apierror.CodeRouteNotFound, "Could not find this route."))
+	})
+
+	router.Use(func(c *gin.Context) {
+		c.Next()
+	})
+
+	return router
+}
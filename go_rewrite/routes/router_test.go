@@ -0,0 +1,218 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/apierror"
+	"backend/config"
+	"backend/controllers"
+	"backend/keyring"
+	"backend/mailer"
+	"backend/models"
+	"backend/ratelimit"
+	"backend/search"
+	"backend/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestHandler builds a handler around cfg and stores, which default to
+// config.Default and store.NewMemoryStores when nil, and a LogMailer.
+func newTestHandler(t *testing.T, cfg *config.Config, stores *store.Stores) *controllers.Handler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	cfg.BcryptCost = 4
+	if stores == nil {
+		stores = store.NewMemoryStores()
+	}
+	keys, err := keyring.Open(context.Background(), keyring.NewMemoryStore(), cfg.KeyPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return controllers.NewHandler(cfg, stores, keys, &mailer.LogMailer{Dir: t.TempDir()}, search.NewMemorySearcher(), ratelimit.NewMemoryStore())
+}
+
+// newTestRouter builds the router around newTestHandler(t, cfg, stores).
+func newTestRouter(t *testing.T, cfg *config.Config, stores *store.Stores) http.Handler {
+	t.Helper()
+	return NewRouter(newTestHandler(t, cfg, stores))
+}
+
+// stallingBlogStore blocks List until the request's context ends, and
+// reports the context's error to the test.
+type stallingBlogStore struct {
+	store.BlogStore
+	entered chan struct{}
+	err     chan error
+}
+
+func newStallingBlogStore(blogs store.BlogStore) *stallingBlogStore {
+	return &stallingBlogStore{BlogStore: blogs, entered: make(chan struct{}), err: make(chan error, 1)}
+}
+
+func (s *stallingBlogStore) List(ctx context.Context, query store.BlogQuery) (*store.BlogPage, error) {
+	close(s.entered)
+	<-ctx.Done()
+	s.err <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+func checkProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	if rec.Code != status {
+		t.Errorf("status = %d, want %d", rec.Code, status)
+	}
+	if got := rec.Header().Get("Content-Type"); got != apierror.ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, apierror.ContentType)
+	}
+	var problem apierror.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	if problem.Status != status || problem.Code != code {
+		t.Errorf("problem = %d %q, want %d %q", problem.Status, problem.Code, status, code)
+	}
+}
+
+func TestRequestCancelled(t *testing.T) {
+	stores := store.NewMemoryStores()
+	blogs := newStallingBlogStore(stores.Blogs)
+	stores.Blogs = blogs
+	router := newTestRouter(t, nil, stores)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-blogs.entered
+		cancel()
+	}()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/blogs", nil).WithContext(ctx))
+
+	if err := <-blogs.err; !errors.Is(err, context.Canceled) {
+		t.Errorf("store saw %v, want context.Canceled", err)
+	}
+	checkProblem(t, rec, http.StatusServiceUnavailable, apierror.CodeCancelled)
+}
+
+func TestRouteDeadlineExceeded(t *testing.T) {
+	stores := store.NewMemoryStores()
+	blogs := newStallingBlogStore(stores.Blogs)
+	stores.Blogs = blogs
+	cfg := config.Default()
+	cfg.RouteTimeouts = []string{"GET /blogs=20ms"}
+	router := newTestRouter(t, cfg, stores)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/blogs", nil))
+
+	if err := <-blogs.err; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("store saw %v, want context.DeadlineExceeded", err)
+	}
+	checkProblem(t, rec, http.StatusGatewayTimeout, apierror.CodeTimeout)
+}
+
+func TestTamperedCursor(t *testing.T) {
+	router := newTestRouter(t, nil, nil)
+
+	forged := &store.Cursor{Sort: store.SortMostCommented, ID: primitive.NewObjectID(), Comments: -1}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/blogs?cursor="+forged.Encode(), nil))
+	checkProblem(t, rec, http.StatusBadRequest, apierror.CodeInvalidQuery)
+
+	// Counts are compared, not allocated, so a huge one is merely a position
+	// ahead of every blog.
+	forged.Comments = 1 << 60
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/blogs?cursor="+forged.Encode(), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("huge comment count: status = %d %s, want 200", rec.Code, rec.Body)
+	}
+}
+
+func TestForwardedForTrust(t *testing.T) {
+	login := func(router http.Handler, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/user/login", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.RemoteAddr = "192.0.2.1:4321"
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name    string
+		proxies []string
+		want    int
+	}{
+		// By default the header is the client's to forge, so rotating it
+		// must not buy more attempts.
+		{"untrusted by default", nil, http.StatusTooManyRequests},
+		{"trusted proxy", []string{"192.0.2.0/24"}, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.LoginIPLimit = ratelimit.Limit{Requests: 1, Per: time.Hour}
+			cfg.TrustedProxies = tt.proxies
+			router := newTestRouter(t, cfg, nil)
+
+			if code := login(router, "198.51.100.1"); code == http.StatusTooManyRequests {
+				t.Fatal("first login was rate limited")
+			}
+			if code := login(router, "198.51.100.2"); code != tt.want {
+				t.Errorf("login with another X-Forwarded-For = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingAuthorLoggedAsWarning(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	stores := store.NewMemoryStores()
+	blog := &models.Blog{Title: "Orphan", Slug: "orphan", Author: primitive.NewObjectID(), Status: models.StatusPublished}
+	if err := stores.Blogs.Create(context.Background(), blog); err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter(t, nil, stores)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/blogs/blog/"+blog.ID.Hex(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s, want 200", rec.Code, rec.Body)
+	}
+
+	found := false
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var record struct{ Level, Msg string }
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("decoding %s: %v", line, err)
+		}
+		if record.Level == slog.LevelError.String() {
+			t.Errorf("logged an error: %s", line)
+		}
+		found = found || (record.Level == slog.LevelWarn.String() && record.Msg == "blog author not found")
+	}
+	if !found {
+		t.Errorf("no warning about the missing author in %s", logs.String())
+	}
+}
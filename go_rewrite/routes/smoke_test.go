@@ -0,0 +1,167 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"backend/apierror"
+	"backend/models"
+)
+
+// call sends body as JSON, with token as the bearer token unless empty, and
+// decodes the response into out when it has the wanted status.
+func call(t *testing.T, router http.Handler, method, path, token string, body any, want int, out any) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != want {
+		t.Fatalf("%s %s = %d %s, want %d", method, path, rec.Code, rec.Body, want)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding %s: %v", method, path, rec.Body, err)
+		}
+	}
+}
+
+func createBlog(t *testing.T, router http.Handler, token, title string) string {
+	t.Helper()
+	var created struct {
+		Blog models.Blog `json:"createdBlog"`
+	}
+	call(t, router, http.MethodPost, "/user/new-blog", token, models.BlogRequest{
+		Title:       title,
+		Description: "Translator's notes",
+		Article:     strings.Repeat("The engine weaves algebraic patterns. ", 20),
+		Tags:        []string{"History"},
+	}, http.StatusCreated, &created)
+	return created.Blog.ID.Hex()
+}
+
+// listAll follows the next cursors of the listing in sort order one blog at
+// a time and returns the IDs in the order they were listed.
+func listAll(t *testing.T, router http.Handler, sort string) []string {
+	t.Helper()
+	var ids []string
+	path := "/blogs?limit=1&sort=" + sort
+	for range 10 {
+		var page models.BlogListResponse
+		call(t, router, http.MethodGet, path, "", nil, http.StatusOK, &page)
+		for _, blog := range page.Blogs {
+			ids = append(ids, blog.ID)
+		}
+		if page.NextCursor == "" {
+			return ids
+		}
+		path = "/blogs?limit=1&cursor=" + page.NextCursor
+	}
+	t.Fatalf("listing by %s did not end after 10 pages", sort)
+	return nil
+}
+
+func TestSmoke(t *testing.T) {
+	router := newTestRouter(t, nil, nil)
+
+	call(t, router, http.MethodPost, "/user/signup", "", models.SignupRequest{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Email:     "ada@example.com",
+		Password:  "correct horse battery",
+	}, http.StatusCreated, nil)
+
+	var user models.UserResponse
+	call(t, router, http.MethodPost, "/user/login", "", models.LoginRequest{
+		Email:    "ada@example.com",
+		Password: "correct horse battery",
+	}, http.StatusOK, &user)
+	if user.Token == "" {
+		t.Fatal("login returned no access token")
+	}
+
+	bid := createBlog(t, router, user.Token, "Notes on the Analytical Engine")
+
+	call(t, router, http.MethodPost, "/blogs/comment/"+bid, user.Token, models.CommentRequest{
+		Comment: "A fine first program.",
+	}, http.StatusCreated, nil)
+
+	var fetched struct {
+		Blog models.BlogResponse `json:"blog"`
+	}
+	call(t, router, http.MethodGet, "/blogs/blog/"+bid, "", nil, http.StatusOK, &fetched)
+
+	blog := fetched.Blog
+	if blog.ID != bid || blog.Title != "Notes on the Analytical Engine" || blog.Status != models.StatusPublished {
+		t.Errorf("blog = %s %q %s, want %s %q published", blog.ID, blog.Title, blog.Status, bid, "Notes on the Analytical Engine")
+	}
+	if blog.Author.FirstName != "Ada" || blog.Author.LastName != "Lovelace" {
+		t.Errorf("author = %+v, want Ada Lovelace", blog.Author)
+	}
+	if len(blog.Tags) != 1 || blog.Tags[0] != "history" {
+		t.Errorf("tags = %v, want [history]", blog.Tags)
+	}
+	if len(blog.Comments) != 1 {
+		t.Fatalf("got %d comments, want 1", len(blog.Comments))
+	}
+	if comment := blog.Comments[0]; comment.Content != "A fine first program." || comment.User.FirstName != "Ada" {
+		t.Errorf("comment = %q by %q, want %q by Ada", comment.Content, comment.User.FirstName, "A fine first program.")
+	}
+
+	// Two more blogs, so that each sort pages through the commented one.
+	second := createBlog(t, router, user.Token, "Sketch of the Analytical Engine")
+	third := createBlog(t, router, user.Token, "A Letter to Babbage")
+	for sort, want := range map[string][]string{
+		"newest":         {third, second, bid},
+		"oldest":         {bid, second, third},
+		"title":          {third, bid, second},
+		"most-commented": {bid, third, second},
+	} {
+		if got := listAll(t, router, sort); !slices.Equal(got, want) {
+			t.Errorf("listing by %s = %v, want %v", sort, got, want)
+		}
+	}
+}
+
+func TestBlankTagRejected(t *testing.T) {
+	router := newTestRouter(t, nil, nil)
+
+	call(t, router, http.MethodPost, "/user/signup", "", models.SignupRequest{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Email:     "ada@example.com",
+		Password:  "correct horse battery",
+	}, http.StatusCreated, nil)
+	var user models.UserResponse
+	call(t, router, http.MethodPost, "/user/login", "", models.LoginRequest{
+		Email:    "ada@example.com",
+		Password: "correct horse battery",
+	}, http.StatusOK, &user)
+
+	var problem apierror.Problem
+	call(t, router, http.MethodPost, "/user/new-blog", user.Token, models.BlogRequest{
+		Title:       "Untitled",
+		Description: "Tags with nothing in them",
+		Article:     strings.Repeat("Nothing to see. ", 40),
+		Tags:        []string{"history", " \t "},
+	}, http.StatusUnprocessableEntity, &problem)
+	if problem.Code != apierror.CodeValidationFailed || len(problem.Errors) != 1 || problem.Errors[0].Field != "tags[1]" {
+		t.Errorf("problem = %+v, want a validation failure of tags[1]", problem)
+	}
+}
@@ -3,19 +3,53 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
+	"backend/policy"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRoutes(router *gin.Engine) {
-	router.POST("/user/signup", controllers.Signup)
-	router.POST("/user/login", controllers.Login)
+func UserRoutes(router *gin.Engine, h *controllers.Handler) {
+	signupLimit := middleware.RateLimit(h.Config, h.Limiter, "signup_ip", h.Config.SignupIPLimit, middleware.ClientIP)
+	loginLimit := middleware.RateLimit(h.Config, h.Limiter, "login_ip", h.Config.LoginIPLimit, middleware.ClientIP)
+
+	router.POST("/user/signup", signupLimit, h.Signup)
+	router.POST("/user/login", loginLimit, h.Login)
+	router.POST("/user/login/2fa", loginLimit, h.LoginTwoFactor)
+	router.POST("/user/refresh", h.Refresh)
+	router.POST("/user/password/forgot", h.ForgotPassword)
+	router.POST("/user/password/reset", h.ResetPassword)
+	router.POST("/user/verify", h.VerifyEmail)
+	router.GET("/user/csrf", h.CSRFToken)
 	
 	authorized:=router.Group("")
-	authorized.Use(middleware.CheckAuth())
+	authorized.Use(middleware.CheckAuth(h.Config, h.Keys, h.Revocations, nil))
+
+	verified := middleware.RequireVerifiedEmail(h.Config, h.Users)
+
+	authorized.POST("/user/logout", h.Logout)
+	authorized.POST("/user/verify/resend", h.ResendVerification)
+	authorized.POST("/user/2fa/setup", h.SetupTwoFactor)
+	authorized.POST("/user/2fa/enable", h.EnableTwoFactor)
+	authorized.POST("/user/2fa/disable", h.DisableTwoFactor)
+	authorized.GET("/user/tokens", h.ListAPITokens)
+	authorized.POST("/user/tokens", h.CreateAPIToken)
+	authorized.DELETE("/user/tokens/:tid", h.RevokeAPIToken)
+
+	// Scripts can use personal access tokens here, within their scopes.
+	scripted := router.Group("")
+	scripted.Use(middleware.CheckAuth(h.Config, h.Keys, h.Revocations, &middleware.APITokens{Tokens: h.APITokens, Users: h.Users}))
+
+	readBlogs := middleware.RequireScope(models.ScopeBlogsRead)
+	writeBlogs := middleware.RequireScope(models.ScopeBlogsWrite)
+
+	scripted.GET("/user/list", readBlogs, h.GetUserBlogs)
+	scripted.POST("/user/new-blog", writeBlogs, middleware.RequirePermission(policy.CreateBlog), verified, h.CreateBlog)
+	scripted.PATCH("/user/:bid", writeBlogs, h.UpdateBlog)
+	scripted.DELETE("/user/:bid", writeBlogs, h.DeleteBlog)
+	scripted.POST("/user/:bid/publish", writeBlogs, h.PublishBlog)
+	scripted.POST("/user/:bid/unpublish", writeBlogs, h.UnpublishBlog)
+	scripted.POST("/user/:bid/archive", writeBlogs, h.ArchiveBlog)
 
-	authorized.GET("/user/list", controllers.GetUserBlogs)
-	authorized.POST("/user/new-blog", controllers.CreateBlog)
-	authorized.PATCH("/user/:bid", controllers.UpdateBlog)
-	authorized.DELETE("/user/:bid", controllers.DeleteBlog)
+	authorized.PATCH("/admin/users/:uid/role", middleware.RequireRole(models.RoleAdmin), h.SetUserRole)
 }
@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"backend/keyring"
+)
+
+// RunKeyRotation rotates the signing keys when due and picks up keys other
+// instances created. It checks every interval, which must be well below
+// keyring.PublishAhead, and returns when ctx is cancelled.
+func RunKeyRotation(ctx context.Context, keys *keyring.Keyring, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		rotate(ctx, keys)
+	}
+}
+
+func rotate(ctx context.Context, keys *keyring.Keyring) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	before := keys.Keys()
+	if err := keys.Rotate(ctx); err != nil {
+		slog.Error("rotating signing keys failed", "error", err)
+		return
+	}
+	after := keys.Keys()
+	if last := after[len(after)-1]; last.ID != before[len(before)-1].ID {
+		slog.Info("new signing key published", "kid", last.ID, "activates_at", last.ActivatesAt)
+	}
+}
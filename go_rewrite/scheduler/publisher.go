@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"backend/store"
+)
+
+// RunPublisher publishes scheduled blogs once their publishAt time has passed.
+// It checks every interval, giving each check timeout, and returns when ctx is
+// cancelled.
+func RunPublisher(ctx context.Context, blogs store.BlogStore, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		publishDue(ctx, blogs, timeout)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func publishDue(ctx context.Context, blogs store.BlogStore, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	count, err := blogs.PublishDue(ctx, time.Now())
+	if err != nil {
+		slog.Error("publishing scheduled blogs failed", "error", err)
+		return
+	}
+	if count > 0 {
+		slog.Info("published scheduled blogs", "count", count)
+	}
+}
@@ -0,0 +1,31 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/store"
+)
+
+// deadlineBlogStore records the deadline PublishDue was called with.
+type deadlineBlogStore struct {
+	store.BlogStore
+	deadline time.Time
+}
+
+func (s *deadlineBlogStore) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	s.deadline, _ = ctx.Deadline()
+	return 0, nil
+}
+
+func TestPublishDueUsesTimeout(t *testing.T) {
+	blogs := &deadlineBlogStore{BlogStore: store.NewMemoryStores().Blogs}
+
+	start := time.Now()
+	publishDue(context.Background(), blogs, 3*time.Second)
+
+	if got := blogs.deadline.Sub(start); got < 3*time.Second || got > 4*time.Second {
+		t.Errorf("deadline %s after start, want 3s", got)
+	}
+}
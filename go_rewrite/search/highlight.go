@@ -0,0 +1,73 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	snippetLength  = 200
+	snippetContext = 60
+)
+
+// highlight returns an excerpt of text around its first matching word, with
+// every match inside the excerpt marked. It reports false when nothing matches.
+func highlight(text string, p parsed) (string, bool) {
+	tokens := tokenize(text)
+	var marked []token
+	for _, t := range tokens {
+		if p.matches(t.text) {
+			marked = append(marked, t)
+		}
+	}
+	if len(marked) == 0 {
+		return "", false
+	}
+
+	start := max(marked[0].start-snippetContext, 0)
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	// Avoid opening on half a word.
+	for _, t := range tokens {
+		if t.start < start && start < t.end {
+			start = t.end
+			break
+		}
+	}
+	end := min(start+snippetLength, len(text))
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	for _, t := range tokens {
+		if t.start < end && end < t.end {
+			end = t.end
+			break
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, t := range marked {
+		if t.start < start {
+			continue
+		}
+		if t.end > end {
+			break
+		}
+		b.WriteString(html.EscapeString(text[pos:t.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[t.start:t.end]))
+		b.WriteString("</mark>")
+		pos = t.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return strings.TrimSpace(b.String()), true
+}
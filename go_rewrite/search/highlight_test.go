@@ -0,0 +1,64 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name, text, query, want string
+	}{
+		{"word", "Learn Go today", "go", "Learn <mark>Go</mark> today"},
+		{"every match", "go, Go and GO", "go", "<mark>go</mark>, <mark>Go</mark> and <mark>GO</mark>"},
+		{"prefix", "Goroutines and gophers", "goph*", "Goroutines and <mark>gophers</mark>"},
+		{"diacritics", "Un café crème", "cafe", "Un <mark>café</mark> crème"},
+		{"escaped", "<b>go</b> & more", "go", "&lt;b&gt;<mark>go</mark>&lt;/b&gt; &amp; more"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := highlight(tt.text, parse(tt.query))
+			if !ok || got != tt.want {
+				t.Errorf("highlight = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+
+	if got, ok := highlight("Learn Rust today", parse("go")); ok {
+		t.Errorf("highlight without a match = %q, true", got)
+	}
+}
+
+func TestHighlightBoundaries(t *testing.T) {
+	// The context before the match starts inside a 6-letter word and the
+	// snippet ends inside a 5-letter one; both are moved to word boundaries.
+	text := strings.Repeat("abcdef ", 40) + "go" + strings.Repeat(" uvwxy", 60)
+	got, _ := highlight(text, parse("go"))
+	if !strings.HasPrefix(got, "… abcdef ") {
+		t.Errorf("snippet starts %q, want a whole word after the ellipsis", got[:min(len(got), 20)])
+	}
+	if !strings.HasSuffix(got, " uvwxy…") {
+		t.Errorf("snippet ends %q, want a whole word before the ellipsis", got[max(len(got)-20, 0):])
+	}
+	if !strings.Contains(got, "<mark>go</mark>") {
+		t.Errorf("snippet %q does not mark the match", got)
+	}
+
+	// Offsets that fall inside multi-byte characters are moved to their start.
+	text = strings.Repeat("—", 50) + " go " + strings.Repeat("—", 100)
+	got, _ = highlight(text, parse("go"))
+	if !utf8.ValidString(got) {
+		t.Errorf("snippet %q is not valid UTF-8", got)
+	}
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("snippet %q is not marked as an excerpt at both ends", got)
+	}
+
+	// Matches outside the excerpt are not marked, so no tag is left open.
+	text = "go " + strings.Repeat("filler ", 60) + "go"
+	got, _ = highlight(text, parse("go"))
+	if strings.Count(got, "<mark>") != 1 || strings.Count(got, "</mark>") != 1 {
+		t.Errorf("snippet %q marks a match outside the excerpt", got)
+	}
+}
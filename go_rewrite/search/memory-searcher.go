@@ -0,0 +1,278 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"backend/models"
+	"backend/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type field struct {
+	weight float64
+	tokens []token
+}
+
+type indexedBlog struct {
+	blog   models.Blog
+	fields []field
+}
+
+// visible mirrors the publisher: scheduled blogs become searchable once
+// their publish time has passed, even before the next publisher run.
+func (d *indexedBlog) visible(now time.Time) bool {
+	switch d.blog.CurrentStatus() {
+	case models.StatusPublished:
+		return true
+	case models.StatusScheduled:
+		return d.blog.PublishAt != nil && !d.blog.PublishAt.After(now)
+	}
+	return false
+}
+
+type indexedComment struct {
+	comment models.Comment
+	field   field
+}
+
+// MemorySearcher is an in-process inverted index, for deployments without
+// MongoDB text search and for tests. It starts empty; Rebuild loads it from
+// the stores.
+type MemorySearcher struct {
+	mu       sync.RWMutex
+	blogs    map[primitive.ObjectID]*indexedBlog
+	comments map[primitive.ObjectID]*indexedComment
+	// postings maps every indexed word to the blogs and comments containing it.
+	postings map[string]map[primitive.ObjectID]struct{}
+}
+
+func NewMemorySearcher() *MemorySearcher {
+	return &MemorySearcher{
+		blogs:    make(map[primitive.ObjectID]*indexedBlog),
+		comments: make(map[primitive.ObjectID]*indexedComment),
+		postings: make(map[string]map[primitive.ObjectID]struct{}),
+	}
+}
+
+// Rebuild indexes every blog in blogs, whatever its status, together with its
+// comments.
+func (s *MemorySearcher) Rebuild(ctx context.Context, blogs store.BlogStore, comments store.CommentStore) error {
+	query := store.BlogQuery{IncludeUnpublished: true, Sort: store.SortOldest, Limit: 200}
+	for {
+		page, err := blogs.List(ctx, query)
+		if err != nil {
+			return err
+		}
+		for i := range page.Blogs {
+			blog := &page.Blogs[i]
+			s.IndexBlog(ctx, blog)
+
+			found, err := comments.FindByIDs(ctx, blog.Comments)
+			if err != nil {
+				return err
+			}
+			for j := range found {
+				s.IndexComment(ctx, &found[j])
+			}
+		}
+		if page.Next == nil {
+			return nil
+		}
+		query.Cursor = page.Next
+	}
+}
+
+func (s *MemorySearcher) IndexBlog(ctx context.Context, blog *models.Blog) error {
+	doc := &indexedBlog{
+		blog: *blog,
+		fields: []field{
+			{titleWeight, tokenize(blog.Title)},
+			{descriptionWeight, tokenize(blog.Description)},
+			{articleWeight, tokenize(blog.Article)},
+		},
+	}
+	doc.blog.Comments = nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.blogs[blog.ID]; ok {
+		s.unpost(blog.ID, old.fields...)
+	}
+	s.blogs[blog.ID] = doc
+	s.post(blog.ID, doc.fields...)
+	return nil
+}
+
+func (s *MemorySearcher) RemoveBlog(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.blogs[id]; ok {
+		s.unpost(id, old.fields...)
+		delete(s.blogs, id)
+	}
+	for cid, c := range s.comments {
+		if c.comment.Blog == id {
+			s.unpost(cid, c.field)
+			delete(s.comments, cid)
+		}
+	}
+	return nil
+}
+
+func (s *MemorySearcher) IndexComment(ctx context.Context, comment *models.Comment) error {
+	doc := &indexedComment{comment: *comment, field: field{commentWeight, tokenize(comment.Content)}}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.comments[comment.ID]; ok {
+		s.unpost(comment.ID, old.field)
+	}
+	s.comments[comment.ID] = doc
+	s.post(comment.ID, doc.field)
+	return nil
+}
+
+func (s *MemorySearcher) RemoveComment(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.comments[id]; ok {
+		s.unpost(id, old.field)
+		delete(s.comments, id)
+	}
+	return nil
+}
+
+func (s *MemorySearcher) post(id primitive.ObjectID, fields ...field) {
+	for _, f := range fields {
+		for _, t := range f.tokens {
+			docs, ok := s.postings[t.text]
+			if !ok {
+				docs = make(map[primitive.ObjectID]struct{})
+				s.postings[t.text] = docs
+			}
+			docs[id] = struct{}{}
+		}
+	}
+}
+
+func (s *MemorySearcher) unpost(id primitive.ObjectID, fields ...field) {
+	for _, f := range fields {
+		for _, t := range f.tokens {
+			delete(s.postings[t.text], id)
+			if len(s.postings[t.text]) == 0 {
+				delete(s.postings, t.text)
+			}
+		}
+	}
+}
+
+func (s *MemorySearcher) Search(ctx context.Context, query Query) (*Result, error) {
+	p := parse(query.Text)
+	if p.empty() {
+		return &Result{Hits: []Hit{}}, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Each term is weighted by how rare it is across the whole index.
+	docCount := float64(len(s.blogs) + len(s.comments))
+	idf := make([]float64, len(p.terms))
+	candidates := make(map[primitive.ObjectID]struct{})
+	for i, t := range p.terms {
+		matching := make(map[primitive.ObjectID]struct{})
+		for word, docs := range s.postings {
+			if t.matches(word) {
+				for id := range docs {
+					matching[id] = struct{}{}
+					candidates[id] = struct{}{}
+				}
+			}
+		}
+		idf[i] = math.Log(1 + docCount/float64(max(len(matching), 1)))
+	}
+
+	now := time.Now()
+	scores := make(map[primitive.ObjectID]*scored)
+	entry := func(blog primitive.ObjectID) *scored {
+		if scores[blog] == nil {
+			scores[blog] = &scored{blog: blog}
+		}
+		return scores[blog]
+	}
+	for id := range candidates {
+		if doc, ok := s.blogs[id]; ok {
+			if !doc.visible(now) {
+				continue
+			}
+			if score := scoreFields(p, idf, doc.fields...); score > 0 {
+				entry(id).score += score
+			}
+			continue
+		}
+		if !query.Comments {
+			continue
+		}
+		doc := s.comments[id]
+		if blog, ok := s.blogs[doc.comment.Blog]; !ok || !blog.visible(now) {
+			continue
+		}
+		if score := scoreFields(p, idf, doc.field); score > 0 {
+			entry(doc.comment.Blog).addComment(id, score)
+		}
+	}
+
+	page, total := rank(scores, query)
+	hits := make([]Hit, 0, len(page))
+	for _, sc := range page {
+		blog := s.blogs[sc.blog].blog
+		var comment *models.Comment
+		if doc, ok := s.comments[sc.comment]; ok {
+			comment = &doc.comment
+		}
+		hits = append(hits, Hit{Blog: blog, Score: sc.score, Snippets: snippets(p, &blog, comment)})
+	}
+	return &Result{Hits: hits, Total: total}, nil
+}
+
+// scoreFields sums a log-scaled TF-IDF per field and rewards every phrase
+// found. Documents missing any of the query's phrases score zero.
+func scoreFields(p parsed, idf []float64, fields ...field) float64 {
+	for _, phrase := range p.phrases {
+		found := false
+		for _, f := range fields {
+			if containsPhrase(f.tokens, phrase) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+
+	var score float64
+	for _, f := range fields {
+		for i, t := range p.terms {
+			tf := 0
+			for _, tok := range f.tokens {
+				if t.matches(tok.text) {
+					tf++
+				}
+			}
+			if tf > 0 {
+				score += f.weight * (1 + math.Log(float64(tf))) * idf[i]
+			}
+		}
+		for _, phrase := range p.phrases {
+			if containsPhrase(f.tokens, phrase) {
+				score += f.weight * float64(len(phrase))
+			}
+		}
+	}
+	return score
+}
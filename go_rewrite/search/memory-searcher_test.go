@@ -0,0 +1,148 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func indexBlog(t *testing.T, s *MemorySearcher, blog models.Blog) primitive.ObjectID {
+	t.Helper()
+	blog.ID = primitive.NewObjectID()
+	if blog.Status == "" {
+		blog.Status = models.StatusPublished
+	}
+	if err := s.IndexBlog(context.Background(), &blog); err != nil {
+		t.Fatal(err)
+	}
+	return blog.ID
+}
+
+func hitIDs(t *testing.T, s *MemorySearcher, query Query) []primitive.ObjectID {
+	t.Helper()
+	result, err := s.Search(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]primitive.ObjectID, len(result.Hits))
+	for i, hit := range result.Hits {
+		ids[i] = hit.Blog.ID
+	}
+	return ids
+}
+
+func TestMemorySearcherRanking(t *testing.T) {
+	s := NewMemorySearcher()
+	inArticle := indexBlog(t, s, models.Blog{Title: "Weekly notes", Article: "Some go code."})
+	inTitle := indexBlog(t, s, models.Blog{Title: "Go tips", Article: "Short ones."})
+	inDescription := indexBlog(t, s, models.Blog{Title: "Notes", Description: "About Go", Article: "Text."})
+	indexBlog(t, s, models.Blog{Title: "Rust tips", Article: "Nothing else."})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []primitive.ObjectID
+	}{
+		{"fields are weighted", "go", []primitive.ObjectID{inTitle, inDescription, inArticle}},
+		{"any word matches", "go rust", nil},
+		{"phrases are required", `"go tips"`, []primitive.ObjectID{inTitle}},
+		{"prefixes match whole words' starts", "cod*", []primitive.ObjectID{inArticle}},
+		{"prefixes do not match inside words", "ips*", []primitive.ObjectID{}},
+		{"nothing to search for", "*", []primitive.ObjectID{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hitIDs(t, s, Query{Text: tt.query})
+			if tt.want == nil {
+				if len(got) != 4 {
+					t.Errorf("got %d hits, want all 4", len(got))
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d hits, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("hit %d = %s, want %s", i, got[i].Hex(), tt.want[i].Hex())
+				}
+			}
+		})
+	}
+}
+
+func TestMemorySearcherPaging(t *testing.T) {
+	s := NewMemorySearcher()
+	var ids []primitive.ObjectID
+	for range 5 {
+		ids = append(ids, indexBlog(t, s, models.Blog{Title: "Go"}))
+	}
+
+	// Equal scores rank the newest first.
+	result, err := s.Search(context.Background(), Query{Text: "go", Offset: 1, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 5 || len(result.Hits) != 2 || result.Hits[0].Blog.ID != ids[3] || result.Hits[1].Blog.ID != ids[2] {
+		t.Errorf("page = %d of %d hits, want blogs 4 and 3 of 5", len(result.Hits), result.Total)
+	}
+	if got := hitIDs(t, s, Query{Text: "go", Offset: 10}); len(got) != 0 {
+		t.Errorf("page past the end has %d hits", len(got))
+	}
+}
+
+func TestMemorySearcherVisibility(t *testing.T) {
+	s := NewMemorySearcher()
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	published := indexBlog(t, s, models.Blog{Title: "Go published"})
+	due := indexBlog(t, s, models.Blog{Title: "Go due", Status: models.StatusScheduled, PublishAt: &past})
+	indexBlog(t, s, models.Blog{Title: "Go later", Status: models.StatusScheduled, PublishAt: &future})
+	indexBlog(t, s, models.Blog{Title: "Go draft", Status: models.StatusDraft})
+	indexBlog(t, s, models.Blog{Title: "Go archived", Status: models.StatusArchived})
+
+	got := hitIDs(t, s, Query{Text: "go"})
+	if len(got) != 2 || got[0] != due || got[1] != published {
+		t.Errorf("got %v, want the published and the due blog", got)
+	}
+}
+
+func TestMemorySearcherComments(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemorySearcher()
+	blog := indexBlog(t, s, models.Blog{Title: "Weekly notes"})
+	draft := indexBlog(t, s, models.Blog{Title: "Draft", Status: models.StatusDraft})
+	for _, c := range []models.Comment{
+		{ID: primitive.NewObjectID(), Blog: blog, Content: "Have you tried goroutines?"},
+		{ID: primitive.NewObjectID(), Blog: draft, Content: "More goroutines"},
+	} {
+		if err := s.IndexComment(ctx, &c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := hitIDs(t, s, Query{Text: "goroutines"}); len(got) != 0 {
+		t.Errorf("comments matched without Comments set: %v", got)
+	}
+	result, err := s.Search(ctx, Query{Text: "goroutines", Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hits) != 1 || result.Hits[0].Blog.ID != blog {
+		t.Fatalf("got %d hits, want only the published blog", len(result.Hits))
+	}
+	snippets := result.Hits[0].Snippets
+	if len(snippets) != 1 || snippets[0].Field != "comment" || snippets[0].Text != "Have you tried <mark>goroutines</mark>?" {
+		t.Errorf("snippets = %v, want the highlighted comment", snippets)
+	}
+
+	if err := s.RemoveBlog(ctx, blog); err != nil {
+		t.Fatal(err)
+	}
+	if got := hitIDs(t, s, Query{Text: "goroutines", Comments: true}); len(got) != 0 {
+		t.Errorf("removed blog still matches through its comments: %v", got)
+	}
+}
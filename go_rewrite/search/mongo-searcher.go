@@ -0,0 +1,287 @@
+package search
+
+import (
+	"context"
+	"regexp"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxCandidates caps how many matches each collection contributes before
+// ranking, which also caps the reported total.
+const maxCandidates = 1000
+
+// publishedStatus matches the store's notion of a published blog, including
+// blogs stored before the status field existed.
+var publishedStatus = bson.M{"$in": bson.A{models.StatusPublished, nil}}
+
+// MongoSearcher queries the text indexes on the blogs and comments
+// collections created by the text-search-indexes migration. MongoDB keeps
+// them current, so the Index and Remove methods do nothing.
+type MongoSearcher struct {
+	blogs    *mongo.Collection
+	comments *mongo.Collection
+}
+
+func NewMongoSearcher(db *mongo.Database) *MongoSearcher {
+	return &MongoSearcher{blogs: db.Collection("blogs"), comments: db.Collection("comments")}
+}
+
+func (s *MongoSearcher) IndexBlog(ctx context.Context, blog *models.Blog) error { return nil }
+
+func (s *MongoSearcher) RemoveBlog(ctx context.Context, id primitive.ObjectID) error { return nil }
+
+func (s *MongoSearcher) IndexComment(ctx context.Context, comment *models.Comment) error { return nil }
+
+func (s *MongoSearcher) RemoveComment(ctx context.Context, id primitive.ObjectID) error { return nil }
+
+type textMatch struct {
+	ID    primitive.ObjectID `bson:"_id"`
+	Blog  primitive.ObjectID `bson:"blog"`
+	Score float64            `bson:"score"`
+}
+
+func (s *MongoSearcher) Search(ctx context.Context, query Query) (*Result, error) {
+	p := parse(query.Text)
+	if p.empty() {
+		return &Result{Hits: []Hit{}}, nil
+	}
+	exact, prefixes := p.split()
+
+	blogMatches, err := s.matches(ctx, s.blogs, bson.M{"status": publishedStatus}, blogFields, exact, prefixes)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[primitive.ObjectID]*scored, len(blogMatches))
+	for _, m := range blogMatches {
+		if scores[m.ID] == nil {
+			scores[m.ID] = &scored{blog: m.ID}
+		}
+		scores[m.ID].score += m.Score
+	}
+
+	if query.Comments {
+		commentMatches, err := s.matches(ctx, s.comments, bson.M{}, commentFields, exact, prefixes)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.addComments(ctx, scores, commentMatches); err != nil {
+			return nil, err
+		}
+	}
+
+	page, total := rank(scores, query)
+	hits := make([]Hit, 0, len(page))
+	if len(page) == 0 {
+		return &Result{Hits: hits, Total: total}, nil
+	}
+
+	blogIDs := make([]primitive.ObjectID, 0, len(page))
+	commentIDs := make([]primitive.ObjectID, 0, len(page))
+	for _, sc := range page {
+		blogIDs = append(blogIDs, sc.blog)
+		if !sc.comment.IsZero() {
+			commentIDs = append(commentIDs, sc.comment)
+		}
+	}
+	blogs, err := findByIDs[models.Blog](ctx, s.blogs, blogIDs)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := findByIDs[models.Comment](ctx, s.comments, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sc := range page {
+		blog, ok := blogs[sc.blog]
+		if !ok {
+			total--
+			continue
+		}
+		var comment *models.Comment
+		if c, ok := comments[sc.comment]; ok {
+			comment = &c
+		}
+		hits = append(hits, Hit{Blog: blog, Score: sc.score, Snippets: snippets(p, &blog, comment)})
+	}
+	return &Result{Hits: hits, Total: total}, nil
+}
+
+// addComments credits comment matches to their blogs, skipping blogs that are
+// not published.
+func (s *MongoSearcher) addComments(ctx context.Context, scores map[primitive.ObjectID]*scored, matches []textMatch) error {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, m.Blog)
+	}
+	filter := bson.M{"_id": bson.M{"$in": ids}, "status": publishedStatus}
+	cursor, err := s.blogs.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var published []textMatch
+	if err := cursor.All(ctx, &published); err != nil {
+		return err
+	}
+	visible := make(map[primitive.ObjectID]bool, len(published))
+	for _, b := range published {
+		visible[b.ID] = true
+	}
+
+	for _, m := range matches {
+		if !visible[m.Blog] {
+			continue
+		}
+		if scores[m.Blog] == nil {
+			scores[m.Blog] = &scored{blog: m.Blog}
+		}
+		scores[m.Blog].addComment(m.ID, m.Score*commentWeight)
+	}
+	return nil
+}
+
+// searchField is a text field of a collection and its weight, as in the
+// text-search-indexes migration.
+type searchField struct {
+	name   string
+	weight float64
+}
+
+var (
+	blogFields    = []searchField{{"title", titleWeight}, {"description", descriptionWeight}, {"article", articleWeight}}
+	commentFields = []searchField{{"content", commentWeight}}
+)
+
+// matches finds the documents of col matching filter and the query, split
+// into its exact terms, for the text index, and its prefix terms. A document
+// found both ways gets both scores.
+func (s *MongoSearcher) matches(ctx context.Context, col *mongo.Collection, filter bson.M, fields []searchField, exact, prefixes parsed) ([]textMatch, error) {
+	var found []textMatch
+	if !exact.empty() {
+		textFilter := bson.M{"$text": bson.M{"$search": exact.mongoSearch()}}
+		for k, v := range filter {
+			textFilter[k] = v
+		}
+		matches, err := textMatches(ctx, col, textFilter, bson.M{"_id": 1, "blog": 1})
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, matches...)
+	}
+	if !prefixes.empty() {
+		matches, err := prefixMatches(ctx, col, filter, fields, prefixes)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, matches...)
+	}
+	return found, nil
+}
+
+// prefixMatches finds the documents with a word starting with one of p's
+// terms, which $text cannot express, by an anchored, case-insensitive regex
+// on each field. The regex does not fold diacritics, so it only preselects;
+// documents are then scored and phrase-checked like the memory searcher's,
+// with every term weighted equally.
+func prefixMatches(ctx context.Context, col *mongo.Collection, filter bson.M, fields []searchField, p parsed) ([]textMatch, error) {
+	clauses := make(bson.A, 0, len(fields)*len(p.terms))
+	projection := bson.M{"_id": 1, "blog": 1}
+	for _, f := range fields {
+		projection[f.name] = 1
+		for _, t := range p.terms {
+			clauses = append(clauses, bson.M{f.name: primitive.Regex{Pattern: prefixPattern(t), Options: "i"}})
+		}
+	}
+	prefixFilter := bson.M{"$or": clauses}
+	for k, v := range filter {
+		prefixFilter[k] = v
+	}
+
+	cursor, err := col.Find(ctx, prefixFilter, options.Find().SetProjection(projection).SetLimit(maxCandidates))
+	if err != nil {
+		return nil, err
+	}
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	idf := make([]float64, len(p.terms))
+	for i := range idf {
+		idf[i] = 1
+	}
+	matches := make([]textMatch, 0, len(docs))
+	for _, doc := range docs {
+		tokenized := make([]field, len(fields))
+		for i, f := range fields {
+			text, _ := doc[f.name].(string)
+			tokenized[i] = field{f.weight, tokenize(text)}
+		}
+		score := scoreFields(p, idf, tokenized...)
+		if score == 0 {
+			continue
+		}
+		m := textMatch{Score: score}
+		m.ID, _ = doc["_id"].(primitive.ObjectID)
+		m.Blog, _ = doc["blog"].(primitive.ObjectID)
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// prefixPattern matches text with a word starting with t.
+func prefixPattern(t term) string {
+	return `(^|[^\p{L}\p{N}])` + regexp.QuoteMeta(t.text)
+}
+
+func textMatches(ctx context.Context, col *mongo.Collection, filter, projection bson.M) ([]textMatch, error) {
+	projection["score"] = bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(projection).
+		SetSort(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetLimit(maxCandidates)
+
+	cursor, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var matches []textMatch
+	if err := cursor.All(ctx, &matches); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func findByIDs[T any](ctx context.Context, col *mongo.Collection, ids []primitive.ObjectID) (map[primitive.ObjectID]T, error) {
+	found := make(map[primitive.ObjectID]T, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	cursor, err := col.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	var docs []bson.Raw
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	for _, raw := range docs {
+		var doc T
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		found[raw.Lookup("_id").ObjectID()] = doc
+	}
+	return found, nil
+}
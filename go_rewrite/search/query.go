@@ -0,0 +1,163 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// token is a word of some text, folded to lower case without diacritics,
+// with its byte offsets in the original text.
+type token struct {
+	text       string
+	start, end int
+}
+
+func tokenize(s string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range s {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		switch {
+		case word && start < 0:
+			start = i
+		case !word && start >= 0:
+			tokens = append(tokens, token{fold(s[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{fold(s[start:]), start, len(s)})
+	}
+	return tokens
+}
+
+func fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+type term struct {
+	text   string
+	prefix bool
+}
+
+func (t term) matches(word string) bool {
+	if t.prefix {
+		return strings.HasPrefix(word, t.text)
+	}
+	return word == t.text
+}
+
+// parsed is a query split into its optional terms and its required phrases.
+// The words of a phrase are also terms, so they count towards the score.
+type parsed struct {
+	terms   []term
+	phrases [][]string
+}
+
+func parse(text string) parsed {
+	var p parsed
+	for i, part := range strings.Split(text, `"`) {
+		// Odd parts sit between quotes; an unbalanced last quote just
+		// turns the rest of the query into a phrase.
+		if i%2 == 1 {
+			words := tokenize(part)
+			if len(words) == 0 {
+				continue
+			}
+			phrase := make([]string, len(words))
+			for j, w := range words {
+				phrase[j] = w.text
+				p.addTerm(term{text: w.text})
+			}
+			if len(phrase) > 1 {
+				p.phrases = append(p.phrases, phrase)
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(part) {
+			words := tokenize(field)
+			for j, w := range words {
+				prefix := j == len(words)-1 && strings.HasSuffix(field, "*")
+				p.addTerm(term{text: w.text, prefix: prefix})
+			}
+		}
+	}
+	return p
+}
+
+func (p *parsed) addTerm(t term) {
+	for _, existing := range p.terms {
+		if existing == t {
+			return
+		}
+	}
+	p.terms = append(p.terms, t)
+}
+
+func (p parsed) empty() bool {
+	return len(p.terms) == 0
+}
+
+func (p parsed) matches(word string) bool {
+	for _, t := range p.terms {
+		if t.matches(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsPhrase reports whether phrase occurs as consecutive tokens.
+func containsPhrase(tokens []token, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		found := true
+		for j, w := range phrase {
+			if tokens[i+j].text != w {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// split separates the prefix terms from the rest, for MongoDB, whose $text
+// has no prefix operator. Both halves keep the phrases, which every match
+// must contain.
+func (p parsed) split() (exact, prefixes parsed) {
+	exact.phrases, prefixes.phrases = p.phrases, p.phrases
+	for _, t := range p.terms {
+		if t.prefix {
+			prefixes.terms = append(prefixes.terms, t)
+		} else {
+			exact.terms = append(exact.terms, t)
+		}
+	}
+	return exact, prefixes
+}
+
+// mongoSearch renders p, which must not have prefix terms, in MongoDB's
+// $text syntax.
+func (p parsed) mongoSearch() string {
+	parts := make([]string, 0, len(p.terms)+len(p.phrases))
+	for _, t := range p.terms {
+		parts = append(parts, t.text)
+	}
+	for _, phrase := range p.phrases {
+		parts = append(parts, `"`+strings.Join(phrase, " ")+`"`)
+	}
+	return strings.Join(parts, " ")
+}
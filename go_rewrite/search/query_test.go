@@ -0,0 +1,108 @@
+package search
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text    string
+		terms   []term
+		phrases [][]string
+	}{
+		{"go", []term{{"go", false}}, nil},
+		{"Go*", []term{{"go", true}}, nil},
+		{"go go GO", []term{{"go", false}}, nil},
+		{"Café", []term{{"cafe", false}}, nil},
+		{"node.js*", []term{{"node", false}, {"js", true}}, nil},
+		{`"Hello, World" go`, []term{{"hello", false}, {"world", false}, {"go", false}}, [][]string{{"hello", "world"}}},
+		{`"single"`, []term{{"single", false}}, nil},
+		{`go "unbalanced phrase`, []term{{"go", false}, {"unbalanced", false}, {"phrase", false}}, [][]string{{"unbalanced", "phrase"}}},
+		{`"" *** "!"`, nil, nil},
+	}
+	for _, tt := range tests {
+		p := parse(tt.text)
+		if !reflect.DeepEqual(p.terms, tt.terms) || !reflect.DeepEqual(p.phrases, tt.phrases) {
+			t.Errorf("parse(%q) = %v %v, want %v %v", tt.text, p.terms, p.phrases, tt.terms, tt.phrases)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	exact, prefixes := parse(`"big data" go* rust`).split()
+
+	if want := []term{{"big", false}, {"data", false}, {"rust", false}}; !reflect.DeepEqual(exact.terms, want) {
+		t.Errorf("exact terms = %v, want %v", exact.terms, want)
+	}
+	if want := []term{{"go", true}}; !reflect.DeepEqual(prefixes.terms, want) {
+		t.Errorf("prefix terms = %v, want %v", prefixes.terms, want)
+	}
+	phrases := [][]string{{"big", "data"}}
+	if !reflect.DeepEqual(exact.phrases, phrases) || !reflect.DeepEqual(prefixes.phrases, phrases) {
+		t.Errorf("phrases = %v and %v, want %v in both", exact.phrases, prefixes.phrases, phrases)
+	}
+	if got, want := exact.mongoSearch(), `big data rust "big data"`; got != want {
+		t.Errorf("mongoSearch = %q, want %q", got, want)
+	}
+}
+
+func TestPrefixPattern(t *testing.T) {
+	// MongoDB applies the pattern with the i option.
+	re := regexp.MustCompile("(?i)" + prefixPattern(term{"go", true}))
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"go", true},
+		{"Golang in practice", true},
+		{"why (Go)?", true},
+		{"tips-and-goroutines", true},
+		{"mongo", false},
+		{"ergo", false},
+		{"ägo", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := re.MatchString(tt.text); got != tt.want {
+			t.Errorf("prefix pattern on %q = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestScoreFields(t *testing.T) {
+	idf := func(p parsed) []float64 {
+		ones := make([]float64, len(p.terms))
+		for i := range ones {
+			ones[i] = 1
+		}
+		return ones
+	}
+	title := func(text string) field { return field{titleWeight, tokenize(text)} }
+	article := func(text string) field { return field{articleWeight, tokenize(text)} }
+
+	p := parse("prog*")
+	if score := scoreFields(p, idf(p), article("Programming in Go")); score <= 0 {
+		t.Errorf("prefix term scored %v on a matching word", score)
+	}
+	if score := scoreFields(p, idf(p), article("Reprogramming")); score != 0 {
+		t.Errorf("prefix term scored %v inside a word", score)
+	}
+
+	p = parse("go")
+	if inTitle, inArticle := scoreFields(p, idf(p), title("Go")), scoreFields(p, idf(p), article("Go")); inTitle <= inArticle {
+		t.Errorf("title match scored %v, not above the article match's %v", inTitle, inArticle)
+	}
+	if once, twice := scoreFields(p, idf(p), article("go")), scoreFields(p, idf(p), article("go go")); twice <= once {
+		t.Errorf("two occurrences scored %v, not above one's %v", twice, once)
+	}
+
+	p = parse(`go "error handling"`)
+	if score := scoreFields(p, idf(p), article("Go handling of errors")); score != 0 {
+		t.Errorf("document without the phrase scored %v", score)
+	}
+	if score := scoreFields(p, idf(p), title("Error handling"), article("in Go")); score <= 0 {
+		t.Errorf("document with the phrase scored %v", score)
+	}
+}
@@ -0,0 +1,117 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Query is a search request. Text supports bare words, "quoted phrases" and
+// word* prefixes: a blog matches when it contains any of the words and every
+// phrase.
+type Query struct {
+	Text string
+	// Comments also matches blogs through the text of their comments.
+	Comments bool
+	Offset   int
+	Limit    int
+}
+
+// Hit is one matching blog with its relevance score and highlighted excerpts.
+type Hit struct {
+	Blog     models.Blog
+	Score    float64
+	Snippets []models.Snippet
+}
+
+type Result struct {
+	Hits  []Hit
+	Total int
+}
+
+// Searcher finds published blogs by their text. Backends that maintain their
+// own index are kept current through the Index and Remove methods; the others
+// implement them as no-ops.
+type Searcher interface {
+	Search(ctx context.Context, query Query) (*Result, error)
+
+	IndexBlog(ctx context.Context, blog *models.Blog) error
+	// RemoveBlog also drops the blog's comments from the index.
+	RemoveBlog(ctx context.Context, id primitive.ObjectID) error
+	IndexComment(ctx context.Context, comment *models.Comment) error
+	RemoveComment(ctx context.Context, id primitive.ObjectID) error
+}
+
+// Field weights shared by both backends, so rankings stay comparable.
+const (
+	titleWeight       = 10
+	descriptionWeight = 5
+	articleWeight     = 1
+	commentWeight     = 1
+)
+
+// scored accumulates a blog's score across the blog itself and its comments.
+type scored struct {
+	blog         primitive.ObjectID
+	score        float64
+	comment      primitive.ObjectID
+	commentScore float64
+}
+
+func (s *scored) addComment(id primitive.ObjectID, score float64) {
+	s.score += score
+	if score > s.commentScore {
+		s.comment, s.commentScore = id, score
+	}
+}
+
+// rank orders the scored blogs by relevance, newest first on ties, and
+// returns the requested page along with the total number of matches.
+func rank(scores map[primitive.ObjectID]*scored, query Query) ([]*scored, int) {
+	all := make([]*scored, 0, len(scores))
+	for _, s := range scores {
+		all = append(all, s)
+	}
+	slices.SortFunc(all, func(a, b *scored) int {
+		switch {
+		case a.score > b.score:
+			return -1
+		case a.score < b.score:
+			return 1
+		}
+		return bytes.Compare(b.blog[:], a.blog[:])
+	})
+
+	total := len(all)
+	start := min(max(query.Offset, 0), total)
+	end := total
+	if query.Limit > 0 {
+		end = min(start+query.Limit, total)
+	}
+	return all[start:end], total
+}
+
+// snippets highlights the blog's matching fields and, when set, the best
+// matching comment.
+func snippets(p parsed, blog *models.Blog, comment *models.Comment) []models.Snippet {
+	result := make([]models.Snippet, 0)
+	for _, f := range []struct{ name, text string }{
+		{"title", blog.Title},
+		{"description", blog.Description},
+		{"article", blog.Article},
+	} {
+		if text, ok := highlight(f.text, p); ok {
+			result = append(result, models.Snippet{Field: f.name, Text: text})
+		}
+	}
+	if comment != nil {
+		if text, ok := highlight(comment.Content, p); ok {
+			result = append(result, models.Snippet{Field: "comment", Text: text})
+		}
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+// Package session carries the access and refresh tokens of browser clients
+// in HttpOnly cookies, out of reach of injected scripts, when the
+// session_cookies setting is on. Cookie sessions are protected from
+// cross-site requests by a double-submit CSRF token: a cookie whose value the
+// client must echo in the X-CSRF-Token header.
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	AccessCookie  = "session"
+	RefreshCookie = "refresh_token"
+	CSRFCookie    = "csrf_token"
+	CSRFHeader    = "X-CSRF-Token"
+
+	// refreshPath limits the refresh cookie to the routes that take it.
+	refreshPath = "/user"
+)
+
+// Set stores the tokens in cookies along with a new CSRF token, which it
+// returns for the client to send back.
+func Set(c *gin.Context, cfg *config.Config, accessToken, refreshToken string) (string, error) {
+	csrf, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	setCookie(c, cfg, AccessCookie, accessToken, "/", cfg.AccessTokenTTL, true)
+	setCookie(c, cfg, RefreshCookie, refreshToken, refreshPath, cfg.RefreshTokenTTL, true)
+	setCookie(c, cfg, CSRFCookie, csrf, "/", cfg.RefreshTokenTTL, false)
+	return csrf, nil
+}
+
+// Clear expires every session cookie.
+func Clear(c *gin.Context, cfg *config.Config) {
+	setCookie(c, cfg, AccessCookie, "", "/", -1, true)
+	setCookie(c, cfg, RefreshCookie, "", refreshPath, -1, true)
+	setCookie(c, cfg, CSRFCookie, "", "/", -1, false)
+}
+
+// CSRFToken returns the request's CSRF cookie, or issues a new one when a
+// session has lost it.
+func CSRFToken(c *gin.Context, cfg *config.Config) (string, error) {
+	if csrf, err := c.Cookie(CSRFCookie); err == nil && csrf != "" {
+		return csrf, nil
+	}
+	csrf, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+	setCookie(c, cfg, CSRFCookie, csrf, "/", cfg.RefreshTokenTTL, false)
+	return csrf, nil
+}
+
+// AccessToken returns the access token cookie, or "" when cookie sessions
+// are off or none was sent.
+func AccessToken(c *gin.Context, cfg *config.Config) string {
+	return cookie(c, cfg, AccessCookie)
+}
+
+// RefreshToken returns the refresh token cookie like AccessToken.
+func RefreshToken(c *gin.Context, cfg *config.Config) string {
+	return cookie(c, cfg, RefreshCookie)
+}
+
+// FromCookies reports whether the request would be authenticated by its
+// cookies, which is when it needs CSRF protection. Requests with an
+// Authorization header never are.
+func FromCookies(c *gin.Context, cfg *config.Config) bool {
+	if c.GetHeader("Authorization") != "" {
+		return false
+	}
+	return AccessToken(c, cfg) != "" || RefreshToken(c, cfg) != ""
+}
+
+func cookie(c *gin.Context, cfg *config.Config, name string) string {
+	if !cfg.SessionCookies {
+		return ""
+	}
+	value, _ := c.Cookie(name)
+	return value
+}
+
+func setCookie(c *gin.Context, cfg *config.Config, name, value, path string, ttl time.Duration, httpOnly bool) {
+	maxAge := -1
+	if ttl > 0 {
+		maxAge = int(ttl.Seconds())
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   cfg.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   cfg.CookieSecure,
+		HttpOnly: httpOnly,
+		SameSite: sameSite(cfg.CookieSameSite),
+	})
+}
+
+func sameSite(mode string) http.SameSite {
+	switch mode {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
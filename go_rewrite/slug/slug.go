@@ -0,0 +1,100 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength keeps generated slugs short enough for readable URLs.
+const MaxLength = 80
+
+// Fallback is used when a title contains nothing that can be transliterated.
+const Fallback = "post"
+
+// letters covers characters that do not decompose into an ASCII base letter
+// plus combining marks, including the Cyrillic and Greek alphabets.
+var letters = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i",
+	'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// MaxTagLength bounds a single normalized tag, in characters.
+const MaxTagLength = 40
+
+// Make turns title into a lowercase, hyphen-separated ASCII slug.
+func Make(title string) string {
+	s := truncate(build(title), MaxLength)
+	if s == "" {
+		return Fallback
+	}
+	return s
+}
+
+// Tag normalizes a user-supplied tag so that spellings differing only in
+// case, Unicode composition or spacing are one tag: "Machine  Learning" and
+// "machine learning" match, while "C++", "C#" and "日本語" stay distinct. It
+// trims and collapses white space, case folds and composes to NFC, and
+// returns "" when nothing remains.
+func Tag(tag string) string {
+	collapsed := strings.Join(strings.Fields(tag), " ")
+	return norm.NFC.String(cases.Fold().String(collapsed))
+}
+
+func build(title string) string {
+	stripMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(stripMarks, strings.ToLower(title))
+	if err != nil {
+		folded = strings.ToLower(title)
+	}
+
+	var b strings.Builder
+	pendingHyphen := false
+	write := func(s string) {
+		if s == "" {
+			return
+		}
+		if pendingHyphen && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		pendingHyphen = false
+		b.WriteString(s)
+	}
+
+	for _, r := range folded {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			write(string(r))
+		case letters[r] != "":
+			write(letters[r])
+		case r == '\'' || r == '’':
+			// "don't" becomes "dont" rather than "don-t".
+		default:
+			pendingHyphen = true
+		}
+	}
+
+	return b.String()
+}
+
+// truncate cuts s to at most max bytes, preferring to stop at a hyphen.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	if i := strings.LastIndexByte(s, '-'); i > max/2 {
+		s = s[:i]
+	}
+	return strings.TrimRight(s, "-")
+}
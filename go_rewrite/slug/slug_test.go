@@ -0,0 +1,28 @@
+package slug
+
+import "testing"
+
+func TestTag(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"Machine Learning", "machine learning"},
+		{"  machine \t learning\n", "machine learning"},
+		{"MACHINE LEARNING", "machine learning"},
+		{"C++", "c++"},
+		{"C#", "c#"},
+		{"C", "c"},
+		{"node.js", "node.js"},
+		{"日本語", "日本語"},
+		{"Straße", "strasse"},
+		{"ΣΊΣΥΦΟΣ", "σίσυφοσ"},
+		// The decomposed and composed é are one tag.
+		{"Cafe\u0301", "caf\u00e9"},
+		{"caf\u00e9", "caf\u00e9"},
+		{"   ", ""},
+	} {
+		if got := Tag(tc.in); got != tc.want {
+			t.Errorf("Tag(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidCursor = errors.New("store: invalid cursor")
+
+type BlogSort string
+
+const (
+	SortNewest        BlogSort = "newest"
+	SortOldest        BlogSort = "oldest"
+	SortTitle         BlogSort = "title"
+	SortMostCommented BlogSort = "most-commented"
+)
+
+func (s BlogSort) Valid() bool {
+	switch s {
+	case SortNewest, SortOldest, SortTitle, SortMostCommented:
+		return true
+	}
+	return false
+}
+
+// BlogQuery narrows BlogStore.List. The zero value lists every published blog,
+// newest first, without a limit.
+type BlogQuery struct {
+	// VisibleTo additionally includes the unpublished blogs written by this user.
+	VisibleTo primitive.ObjectID
+	// IncludeUnpublished lists blogs in every status, for maintenance jobs
+	// that need to see the whole collection.
+	IncludeUnpublished bool
+	// Tag, when set, only matches blogs carrying this normalized tag.
+	Tag    string
+	Author primitive.ObjectID
+	// CreatedFrom and CreatedTo bound the creation time taken from the blog's
+	// ObjectID; CreatedTo is exclusive. Zero values leave that side open.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	Sort  BlogSort
+	Limit int
+	// Cursor continues a previous page; it must have been issued for the same Sort.
+	Cursor *Cursor
+}
+
+func (q BlogQuery) sort() BlogSort {
+	if q.Sort == "" {
+		return SortNewest
+	}
+	return q.Sort
+}
+
+// BlogPage is one page of a listing. Next and Prev are nil at either end.
+type BlogPage struct {
+	Blogs []models.Blog
+	Next  *Cursor
+	Prev  *Cursor
+}
+
+// Cursor marks a position in a sorted listing. Clients receive it encoded
+// and must treat it as opaque.
+type Cursor struct {
+	Sort     BlogSort           `json:"s"`
+	ID       primitive.ObjectID `json:"i"`
+	Title    string             `json:"t,omitempty"`
+	Comments int                `json:"c,omitempty"`
+	// Backward cursors return the page before the position instead of after it.
+	Backward bool `json:"b,omitempty"`
+}
+
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || !c.Sort.Valid() || c.ID.IsZero() || c.Comments < 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func cursorAt(sort BlogSort, blog *models.Blog, backward bool) *Cursor {
+	c := &Cursor{Sort: sort, ID: blog.ID, Backward: backward}
+	switch sort {
+	case SortTitle:
+		c.Title = blog.Title
+	case SortMostCommented:
+		c.Comments = len(blog.Comments)
+	}
+	return c
+}
+
+// position holds the fields the sort orders compare, which is all a cursor
+// keeps of the blog it was issued at.
+type position struct {
+	id       primitive.ObjectID
+	title    string
+	comments int
+}
+
+func positionOf(blog *models.Blog) position {
+	return position{id: blog.ID, title: blog.Title, comments: len(blog.Comments)}
+}
+
+// comparePositions orders a before b (negative) according to sort, breaking
+// ties by ObjectID in the direction of the primary key.
+func comparePositions(sort BlogSort, a, b position) int {
+	byID := bytes.Compare(a.id[:], b.id[:])
+	switch sort {
+	case SortOldest:
+		return byID
+	case SortTitle:
+		if c := strings.Compare(a.title, b.title); c != 0 {
+			return c
+		}
+		return byID
+	case SortMostCommented:
+		if c := cmp.Compare(b.comments, a.comments); c != 0 {
+			return c
+		}
+		return -byID
+	default:
+		return -byID
+	}
+}
+
+func compareBlogs(sort BlogSort, a, b *models.Blog) int {
+	return comparePositions(sort, positionOf(a), positionOf(b))
+}
+
+// compareToCursor orders blog relative to the position c marks.
+func compareToCursor(blog *models.Blog, c *Cursor) int {
+	return comparePositions(c.Sort, positionOf(blog), position{id: c.ID, title: c.Title, comments: c.Comments})
+}
+
+// pageFrom turns the blogs fetched for query, in travel direction and with up
+// to one extra element, into a page with cursors in both directions.
+func pageFrom(query BlogQuery, blogs []models.Blog) *BlogPage {
+	sort := query.sort()
+	backward := query.Cursor != nil && query.Cursor.Backward
+	hasMore := query.Limit > 0 && len(blogs) > query.Limit
+	if hasMore {
+		blogs = blogs[:query.Limit]
+	}
+	if backward {
+		for i, j := 0, len(blogs)-1; i < j; i, j = i+1, j-1 {
+			blogs[i], blogs[j] = blogs[j], blogs[i]
+		}
+	}
+
+	page := &BlogPage{Blogs: blogs}
+	if len(blogs) == 0 {
+		return page
+	}
+	first, last := &blogs[0], &blogs[len(blogs)-1]
+	if (!backward && hasMore) || (backward && query.Cursor != nil) {
+		page.Next = cursorAt(sort, last, false)
+	}
+	if (backward && hasMore) || (!backward && query.Cursor != nil) {
+		page.Prev = cursorAt(sort, first, true)
+	}
+	return page
+}
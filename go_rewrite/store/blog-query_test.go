@@ -0,0 +1,60 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	valid := &Cursor{Sort: SortMostCommented, ID: id, Comments: 3, Backward: true}
+	got, err := DecodeCursor(valid.Encode())
+	if err != nil || *got != *valid {
+		t.Fatalf("DecodeCursor(Encode()) = %+v, %v, want %+v", got, err, valid)
+	}
+
+	for name, encoded := range map[string]string{
+		"not base64":        "%%%",
+		"not JSON":          raw("cursor"),
+		"unknown sort":      (&Cursor{Sort: "popular", ID: id}).Encode(),
+		"no ID":             (&Cursor{Sort: SortNewest}).Encode(),
+		"negative comments": (&Cursor{Sort: SortMostCommented, ID: id, Comments: -1}).Encode(),
+	} {
+		if _, err := DecodeCursor(encoded); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: DecodeCursor = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+func TestCompareToCursor(t *testing.T) {
+	blog := &models.Blog{ID: primitive.NewObjectID(), Title: "M", Comments: make([]primitive.ObjectID, 2)}
+	older := primitive.NewObjectIDFromTimestamp(blog.ID.Timestamp().Add(-time.Second))
+	newer := primitive.NewObjectIDFromTimestamp(blog.ID.Timestamp().Add(time.Second))
+
+	for _, tc := range []struct {
+		name   string
+		cursor Cursor
+		want   int
+	}{
+		{"newest after newer", Cursor{Sort: SortNewest, ID: newer}, 1},
+		{"oldest after older", Cursor{Sort: SortOldest, ID: older}, 1},
+		{"title before N", Cursor{Sort: SortTitle, ID: older, Title: "N"}, -1},
+		{"title tie by ID", Cursor{Sort: SortTitle, ID: older, Title: "M"}, 1},
+		{"more comments first", Cursor{Sort: SortMostCommented, ID: older, Comments: 1}, -1},
+		{"fewer comments after", Cursor{Sort: SortMostCommented, ID: older, Comments: 5}, 1},
+		// A forged count must not be materialized, however large.
+		{"huge count", Cursor{Sort: SortMostCommented, ID: older, Comments: 1 << 60}, 1},
+	} {
+		if got := compareToCursor(blog, &tc.cursor); got != tc.want {
+			t.Errorf("%s: compareToCursor = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
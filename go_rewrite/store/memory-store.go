@@ -0,0 +1,869 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewMemoryStores returns stores backed by process memory. They behave like the
+// MongoDB stores and are meant for tests and local development without a database.
+func NewMemoryStores() *Stores {
+	return &Stores{
+		Users:    &memoryUserStore{docs: map[primitive.ObjectID]models.User{}},
+		Blogs:    &memoryBlogStore{docs: map[primitive.ObjectID]models.Blog{}},
+		Comments: &memoryCommentStore{docs: map[primitive.ObjectID]models.Comment{}},
+
+		RefreshTokens: &memoryRefreshTokenStore{docs: map[primitive.ObjectID]models.RefreshToken{}},
+		Revocations:   &memoryRevocationStore{docs: map[string]models.RevokedToken{}},
+		OneTimeTokens: &memoryOneTimeTokenStore{docs: map[primitive.ObjectID]models.OneTimeToken{}},
+		APITokens:     &memoryAPITokenStore{docs: map[primitive.ObjectID]models.APIToken{}},
+
+		Tx: compensatingTransactor{},
+		DB: memoryPinger{},
+	}
+}
+
+// memoryPinger is always reachable, as there is nothing to connect to.
+type memoryPinger struct{}
+
+func (memoryPinger) Ping(context.Context) error { return nil }
+
+type memoryUserStore struct {
+	mu   sync.RWMutex
+	docs map[primitive.ObjectID]models.User
+}
+
+func (s *memoryUserStore) Create(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range s.docs {
+		if doc.Email == user.Email {
+			return ErrDuplicate
+		}
+	}
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	doc := *user
+	doc.Blogs = slices.Clone(user.Blogs)
+	s.docs[doc.ID] = doc
+	return nil
+}
+
+func (s *memoryUserStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	doc.Blogs = slices.Clone(doc.Blogs)
+	return &doc, nil
+}
+
+func (s *memoryUserStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, doc := range s.docs {
+		if doc.Email == email {
+			doc.Blogs = slices.Clone(doc.Blogs)
+			return &doc, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryUserStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]models.User, 0, len(ids))
+	for _, id := range ids {
+		if doc, ok := s.docs[id]; ok {
+			doc.Blogs = slices.Clone(doc.Blogs)
+			users = append(users, doc)
+		}
+	}
+	return users, nil
+}
+
+func (s *memoryUserStore) UpdatePassword(ctx context.Context, id primitive.ObjectID, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	doc.Password = hash
+	s.docs[id] = doc
+	return nil
+}
+
+func (s *memoryUserStore) SetEmailVerified(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	doc.EmailVerified = true
+	s.docs[id] = doc
+	return nil
+}
+
+func (s *memoryUserStore) SetRole(ctx context.Context, id primitive.ObjectID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	doc.Role = role
+	s.docs[id] = doc
+	return nil
+}
+
+func (s *memoryUserStore) SetTwoFactor(ctx context.Context, id primitive.ObjectID, twoFactor models.TwoFactor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	doc.TwoFactor = twoFactor
+	doc.TwoFactor.RecoveryCodes = slices.Clone(twoFactor.RecoveryCodes)
+	s.docs[id] = doc
+	return nil
+}
+
+func (s *memoryUserStore) UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok || !doc.TwoFactor.Enabled || doc.TwoFactor.LastStep >= step {
+		return ErrNotFound
+	}
+	doc.TwoFactor.LastStep = step
+	s.docs[id] = doc
+	return nil
+}
+
+func (s *memoryUserStore) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok || !doc.TwoFactor.Enabled || !slices.Contains(doc.TwoFactor.RecoveryCodes, hash) {
+		return ErrNotFound
+	}
+	doc.TwoFactor.RecoveryCodes = slices.DeleteFunc(slices.Clone(doc.TwoFactor.RecoveryCodes), func(h string) bool { return h == hash })
+	s.docs[id] = doc
+	return nil
+}
+
+func (s *memoryUserStore) AddBlog(ctx context.Context, uid, bid primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[uid]
+	if !ok {
+		return ErrNotFound
+	}
+	doc.Blogs = append(slices.Clone(doc.Blogs), bid)
+	s.docs[uid] = doc
+	return nil
+}
+
+func (s *memoryUserStore) RemoveBlog(ctx context.Context, uid, bid primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doc, ok := s.docs[uid]; ok {
+		doc.Blogs = removeID(doc.Blogs, bid)
+		s.docs[uid] = doc
+	}
+	return nil
+}
+
+type memoryBlogStore struct {
+	mu    sync.RWMutex
+	docs  map[primitive.ObjectID]models.Blog
+	order []primitive.ObjectID
+}
+
+func (s *memoryBlogStore) Create(ctx context.Context, blog *models.Blog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.slugTaken(blog.Slug, blog.ID) {
+		return ErrDuplicate
+	}
+	if blog.ID.IsZero() {
+		blog.ID = primitive.NewObjectID()
+	}
+	doc := cloneBlog(*blog)
+	s.docs[doc.ID] = doc
+	s.order = append(s.order, doc.ID)
+	return nil
+}
+
+func (s *memoryBlogStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Blog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	doc = cloneBlog(doc)
+	return &doc, nil
+}
+
+func (s *memoryBlogStore) FindBySlug(ctx context.Context, slug string) (*models.Blog, error) {
+	blogs := s.filter(func(b models.Blog) bool {
+		return b.Slug == slug || slices.Contains(b.SlugHistory, slug)
+	})
+	if len(blogs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &blogs[0], nil
+}
+
+func (s *memoryBlogStore) SlugInUse(ctx context.Context, slug string, except primitive.ObjectID) (bool, error) {
+	blogs := s.filter(func(b models.Blog) bool {
+		return b.ID != except && (b.Slug == slug || slices.Contains(b.SlugHistory, slug))
+	})
+	return len(blogs) > 0, nil
+}
+
+func (s *memoryBlogStore) List(ctx context.Context, query BlogQuery) (*BlogPage, error) {
+	blogs := s.filter(queryMatcher(query))
+
+	sort, c := query.sort(), query.Cursor
+	backward := c != nil && c.Backward
+	slices.SortFunc(blogs, func(a, b models.Blog) int {
+		if backward {
+			return compareBlogs(sort, &b, &a)
+		}
+		return compareBlogs(sort, &a, &b)
+	})
+
+	if c != nil {
+		blogs = slices.DeleteFunc(blogs, func(b models.Blog) bool {
+			if backward {
+				return compareToCursor(&b, c) >= 0
+			}
+			return compareToCursor(&b, c) <= 0
+		})
+	}
+	if query.Limit > 0 && len(blogs) > query.Limit+1 {
+		blogs = blogs[:query.Limit+1]
+	}
+	return pageFrom(query, blogs), nil
+}
+
+func (s *memoryBlogStore) Count(ctx context.Context, query BlogQuery) (int64, error) {
+	return int64(len(s.filter(queryMatcher(query)))), nil
+}
+
+func queryMatcher(query BlogQuery) func(models.Blog) bool {
+	return func(b models.Blog) bool {
+		if query.Tag != "" && !slices.Contains(b.Tags, query.Tag) {
+			return false
+		}
+		if !query.Author.IsZero() && b.Author != query.Author {
+			return false
+		}
+		created := b.ID.Timestamp()
+		if !query.CreatedFrom.IsZero() && created.Before(query.CreatedFrom) {
+			return false
+		}
+		if !query.CreatedTo.IsZero() && !created.Before(query.CreatedTo) {
+			return false
+		}
+		return query.IncludeUnpublished || b.IsPublished() || (!query.VisibleTo.IsZero() && b.Author == query.VisibleTo)
+	}
+}
+
+// filter returns matching blogs in insertion order, which mirrors MongoDB's natural order.
+func (s *memoryBlogStore) filter(match func(models.Blog) bool) []models.Blog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blogs := make([]models.Blog, 0)
+	for _, id := range s.order {
+		doc, ok := s.docs[id]
+		if !ok || !match(doc) {
+			continue
+		}
+		blogs = append(blogs, cloneBlog(doc))
+	}
+	return blogs
+}
+
+// slugTaken mirrors the unique index on slug: it reports whether a blog other
+// than except currently has slug. The caller must hold s.mu.
+func (s *memoryBlogStore) slugTaken(slug string, except primitive.ObjectID) bool {
+	if slug == "" {
+		return false
+	}
+	for id, doc := range s.docs {
+		if id != except && doc.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryBlogStore) Update(ctx context.Context, blog *models.Blog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[blog.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if s.slugTaken(blog.Slug, blog.ID) {
+		return ErrDuplicate
+	}
+	doc.Title = blog.Title
+	doc.Slug = blog.Slug
+	doc.SlugHistory = slices.Clone(blog.SlugHistory)
+	doc.Description = blog.Description
+	doc.Article = blog.Article
+	doc.Tags = slices.Clone(blog.Tags)
+	s.docs[blog.ID] = doc
+	return nil
+}
+
+func (s *memoryBlogStore) UpdateStatus(ctx context.Context, blog *models.Blog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[blog.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	doc.Status = blog.Status
+	doc.PublishAt = blog.PublishAt
+	doc.PublishedAt = blog.PublishedAt
+	s.docs[blog.ID] = doc
+	return nil
+}
+
+func (s *memoryBlogStore) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for id, doc := range s.docs {
+		if doc.Status == models.StatusScheduled && doc.PublishAt != nil && !doc.PublishAt.After(now) {
+			doc.Status = models.StatusPublished
+			doc.PublishedAt = doc.PublishAt
+			s.docs[id] = doc
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryBlogStore) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	byTag := map[string]int{}
+	for _, blog := range s.filter(func(b models.Blog) bool { return b.IsPublished() }) {
+		for _, tag := range blog.Tags {
+			byTag[tag]++
+		}
+	}
+
+	counts := make([]models.TagCount, 0, len(byTag))
+	for tag, count := range byTag {
+		counts = append(counts, models.TagCount{Tag: tag, Count: count})
+	}
+	slices.SortFunc(counts, func(a, b models.TagCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	return counts, nil
+}
+
+func (s *memoryBlogStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.docs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.docs, id)
+	s.order = removeID(s.order, id)
+	return nil
+}
+
+func (s *memoryBlogStore) AddComment(ctx context.Context, bid, cid primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[bid]
+	if !ok {
+		return ErrNotFound
+	}
+	doc.Comments = append(slices.Clone(doc.Comments), cid)
+	s.docs[bid] = doc
+	return nil
+}
+
+func (s *memoryBlogStore) RemoveComment(ctx context.Context, cid primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, doc := range s.docs {
+		if slices.Contains(doc.Comments, cid) {
+			doc.Comments = removeID(doc.Comments, cid)
+			s.docs[id] = doc
+			return nil
+		}
+	}
+	return nil
+}
+
+type memoryCommentStore struct {
+	mu   sync.RWMutex
+	docs map[primitive.ObjectID]models.Comment
+}
+
+func (s *memoryCommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if comment.ID.IsZero() {
+		comment.ID = primitive.NewObjectID()
+	}
+	s.docs[comment.ID] = *comment
+	return nil
+}
+
+func (s *memoryCommentStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &doc, nil
+}
+
+func (s *memoryCommentStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comments := make([]models.Comment, 0, len(ids))
+	for _, id := range ids {
+		if doc, ok := s.docs[id]; ok {
+			comments = append(comments, doc)
+		}
+	}
+	return comments, nil
+}
+
+func (s *memoryCommentStore) UpdateContent(ctx context.Context, id primitive.ObjectID, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	doc.Content = content
+	s.docs[id] = doc
+	return nil
+}
+
+func (s *memoryCommentStore) MarkDeleted(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	doc.Deleted = true
+	doc.Content = ""
+	s.docs[id] = doc
+	return nil
+}
+
+func (s *memoryCommentStore) CountReplies(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int64
+	for _, doc := range s.docs {
+		if doc.ParentID == id {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryCommentStore) ListReplies(ctx context.Context, bid, parent primitive.ObjectID, offset, limit int) ([]models.Comment, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var replies []models.Comment
+	for _, doc := range s.docs {
+		if doc.Blog == bid && doc.ParentID == parent {
+			replies = append(replies, doc)
+		}
+	}
+	slices.SortFunc(replies, func(a, b models.Comment) int {
+		if c := a.Date.Compare(b.Date); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+
+	total := len(replies)
+	start := min(offset, total)
+	end := min(start+limit, total)
+	return slices.Clone(replies[start:end]), int64(total), nil
+}
+
+func (s *memoryCommentStore) FindReplies(ctx context.Context, parents []primitive.ObjectID) ([]models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	replies := make([]models.Comment, 0)
+	for _, doc := range s.docs {
+		if !doc.ParentID.IsZero() && slices.Contains(parents, doc.ParentID) {
+			replies = append(replies, doc)
+		}
+	}
+	return replies, nil
+}
+
+func (s *memoryCommentStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.docs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.docs, id)
+	return nil
+}
+
+func (s *memoryCommentStore) DeleteByBlog(ctx context.Context, bid primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, doc := range s.docs {
+		if doc.Blog == bid {
+			delete(s.docs, id)
+		}
+	}
+	return nil
+}
+
+type memoryRefreshTokenStore struct {
+	mu   sync.RWMutex
+	docs map[primitive.ObjectID]models.RefreshToken
+}
+
+func (s *memoryRefreshTokenStore) Create(ctx context.Context, token *models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	s.docs[token.ID] = *token
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, doc := range s.docs {
+		if doc.TokenHash == hash {
+			return &doc, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryRefreshTokenStore) Consume(ctx context.Context, id primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok || doc.Used || doc.Revoked {
+		return ErrNotFound
+	}
+	doc.Used = true
+	s.docs[id] = doc
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeFamily(ctx context.Context, family primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, doc := range s.docs {
+		if doc.Family == family {
+			doc.Revoked = true
+			s.docs[id] = doc
+		}
+	}
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeUser(ctx context.Context, uid primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, doc := range s.docs {
+		if doc.User == uid {
+			doc.Revoked = true
+			s.docs[id] = doc
+		}
+	}
+	return nil
+}
+
+type memoryRevocationStore struct {
+	mu   sync.RWMutex
+	docs map[string]models.RevokedToken
+}
+
+func (s *memoryRevocationStore) Revoke(ctx context.Context, token *models.RevokedToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.docs[token.JTI] = *token
+	return nil
+}
+
+func (s *memoryRevocationStore) RevokeUser(ctx context.Context, uid primitive.ObjectID, cutoff, expiresAt time.Time) error {
+	return s.Revoke(ctx, &models.RevokedToken{
+		JTI:          userRevocationKey(uid),
+		User:         uid,
+		IssuedBefore: cutoff,
+		ExpiresAt:    expiresAt,
+	})
+}
+
+func (s *memoryRevocationStore) IsRevoked(ctx context.Context, jti string, uid primitive.ObjectID, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	if doc, ok := s.docs[jti]; ok && doc.ExpiresAt.After(now) {
+		return true, nil
+	}
+	doc, ok := s.docs[userRevocationKey(uid)]
+	return ok && doc.ExpiresAt.After(now) && !issuedAt.After(doc.IssuedBefore), nil
+}
+
+type memoryOneTimeTokenStore struct {
+	mu   sync.RWMutex
+	docs map[primitive.ObjectID]models.OneTimeToken
+}
+
+func (s *memoryOneTimeTokenStore) Create(ctx context.Context, token *models.OneTimeToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	s.docs[token.ID] = *token
+	return nil
+}
+
+func (s *memoryOneTimeTokenStore) Consume(ctx context.Context, purpose, hash string) (*models.OneTimeToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, doc := range s.docs {
+		if doc.Purpose == purpose && doc.TokenHash == hash && !doc.Used && doc.ExpiresAt.After(now) {
+			doc.Used = true
+			s.docs[id] = doc
+			return &doc, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryOneTimeTokenStore) Invalidate(ctx context.Context, uid primitive.ObjectID, purpose string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, doc := range s.docs {
+		if doc.User == uid && doc.Purpose == purpose {
+			doc.Used = true
+			s.docs[id] = doc
+		}
+	}
+	return nil
+}
+
+func (s *memoryOneTimeTokenStore) Latest(ctx context.Context, uid primitive.ObjectID, purpose string) (*models.OneTimeToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var latest *models.OneTimeToken
+	for _, doc := range s.docs {
+		if doc.User == uid && doc.Purpose == purpose && (latest == nil || doc.CreatedAt.After(latest.CreatedAt)) {
+			latest = &doc
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+type memoryAPITokenStore struct {
+	mu   sync.RWMutex
+	docs map[primitive.ObjectID]models.APIToken
+}
+
+func apiTokenLive(doc models.APIToken, now time.Time) bool {
+	return doc.ExpiresAt == nil || doc.ExpiresAt.After(now)
+}
+
+// cloneAPIToken copies the slices and pointers of t so callers cannot mutate
+// stored documents.
+func cloneAPIToken(t models.APIToken) models.APIToken {
+	t.Scopes = slices.Clone(t.Scopes)
+	if t.ExpiresAt != nil {
+		expiresAt := *t.ExpiresAt
+		t.ExpiresAt = &expiresAt
+	}
+	if t.LastUsedAt != nil {
+		lastUsedAt := *t.LastUsedAt
+		t.LastUsedAt = &lastUsedAt
+	}
+	return t
+}
+
+func (s *memoryAPITokenStore) Create(ctx context.Context, token *models.APIToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range s.docs {
+		if doc.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	s.docs[token.ID] = cloneAPIToken(*token)
+	return nil
+}
+
+func (s *memoryAPITokenStore) FindByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for _, doc := range s.docs {
+		if doc.TokenHash == hash && apiTokenLive(doc, now) {
+			token := cloneAPIToken(doc)
+			return &token, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryAPITokenStore) ListByUser(ctx context.Context, uid primitive.ObjectID) ([]models.APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	tokens := []models.APIToken{}
+	for _, doc := range s.docs {
+		if doc.User == uid && apiTokenLive(doc, now) {
+			tokens = append(tokens, cloneAPIToken(doc))
+		}
+	}
+	slices.SortFunc(tokens, func(a, b models.APIToken) int { return b.ID.Timestamp().Compare(a.ID.Timestamp()) })
+	return tokens, nil
+}
+
+func (s *memoryAPITokenStore) CountByUser(ctx context.Context, uid primitive.ObjectID) (int64, error) {
+	tokens, err := s.ListByUser(ctx, uid)
+	return int64(len(tokens)), err
+}
+
+func (s *memoryAPITokenStore) MarkUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return nil
+	}
+	if doc.LastUsedAt == nil || at.After(*doc.LastUsedAt) {
+		doc.LastUsedAt = &at
+		s.docs[id] = doc
+	}
+	return nil
+}
+
+func (s *memoryAPITokenStore) Delete(ctx context.Context, id, uid primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok || doc.User != uid {
+		return ErrNotFound
+	}
+	delete(s.docs, id)
+	return nil
+}
+
+func (s *memoryAPITokenStore) DeleteByUser(ctx context.Context, uid primitive.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, doc := range s.docs {
+		if doc.User == uid {
+			delete(s.docs, id)
+		}
+	}
+	return nil
+}
+
+// cloneBlog copies the slices of b so callers cannot mutate stored documents.
+func cloneBlog(b models.Blog) models.Blog {
+	b.Comments = slices.Clone(b.Comments)
+	b.SlugHistory = slices.Clone(b.SlugHistory)
+	b.Tags = slices.Clone(b.Tags)
+	return b
+}
+
+func removeID(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	return slices.DeleteFunc(slices.Clone(ids), func(v primitive.ObjectID) bool { return v == id })
+}
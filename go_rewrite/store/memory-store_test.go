@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryUserStore(t *testing.T) {
+	ctx := context.Background()
+	users := NewMemoryStores().Users
+
+	user := &models.User{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID.IsZero() {
+		t.Fatal("Create did not assign an ID")
+	}
+
+	found, err := users.FindByEmail(ctx, "ada@example.com")
+	if err != nil || found.ID != user.ID {
+		t.Fatalf("FindByEmail = %v, %v, want %s", found, err, user.ID.Hex())
+	}
+	if _, err := users.FindByEmail(ctx, "bob@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindByEmail of unknown email = %v, want ErrNotFound", err)
+	}
+	if _, err := users.FindByID(ctx, primitive.NewObjectID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindByID of unknown id = %v, want ErrNotFound", err)
+	}
+
+	bid := primitive.NewObjectID()
+	if err := users.AddBlog(ctx, user.ID, bid); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.AddBlog(ctx, primitive.NewObjectID(), bid); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddBlog to unknown user = %v, want ErrNotFound", err)
+	}
+
+	// Callers must not be able to change stored documents through results.
+	found, err = users.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found.Blogs[0] = primitive.NilObjectID
+	found, _ = users.FindByID(ctx, user.ID)
+	if !slices.Equal(found.Blogs, []primitive.ObjectID{bid}) {
+		t.Fatalf("blogs = %v, want [%s]", found.Blogs, bid.Hex())
+	}
+
+	if err := users.RemoveBlog(ctx, user.ID, bid); err != nil {
+		t.Fatal(err)
+	}
+	found, _ = users.FindByID(ctx, user.ID)
+	if len(found.Blogs) != 0 {
+		t.Errorf("blogs after RemoveBlog = %v, want none", found.Blogs)
+	}
+
+	listed, err := users.FindByIDs(ctx, []primitive.ObjectID{primitive.NewObjectID(), user.ID})
+	if err != nil || len(listed) != 1 || listed[0].ID != user.ID {
+		t.Errorf("FindByIDs = %v, %v, want only the stored user", listed, err)
+	}
+}
+
+func TestMemoryBlogStore(t *testing.T) {
+	ctx := context.Background()
+	blogs := NewMemoryStores().Blogs
+
+	first := &models.Blog{Title: "First", Author: primitive.NewObjectID()}
+	second := &models.Blog{Title: "Second", Author: primitive.NewObjectID()}
+	for _, blog := range []*models.Blog{first, second} {
+		if err := blogs.Create(ctx, blog); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cid := primitive.NewObjectID()
+	if err := blogs.AddComment(ctx, second.ID, cid); err != nil {
+		t.Fatal(err)
+	}
+	if err := blogs.AddComment(ctx, primitive.NewObjectID(), cid); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddComment to unknown blog = %v, want ErrNotFound", err)
+	}
+	found, err := blogs.FindByID(ctx, second.ID)
+	if err != nil || !slices.Equal(found.Comments, []primitive.ObjectID{cid}) {
+		t.Fatalf("comments = %v, %v, want [%s]", found, err, cid.Hex())
+	}
+
+	// RemoveComment finds the blog by the comment alone.
+	if err := blogs.RemoveComment(ctx, cid); err != nil {
+		t.Fatal(err)
+	}
+	found, _ = blogs.FindByID(ctx, second.ID)
+	if len(found.Comments) != 0 {
+		t.Errorf("comments after RemoveComment = %v, want none", found.Comments)
+	}
+
+	found.Title = "Second, revised"
+	if err := blogs.Update(ctx, found); err != nil {
+		t.Fatal(err)
+	}
+	found, _ = blogs.FindByID(ctx, second.ID)
+	if found.Title != "Second, revised" {
+		t.Errorf("title after Update = %q", found.Title)
+	}
+	if err := blogs.Update(ctx, &models.Blog{ID: primitive.NewObjectID(), Title: "Ghost"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update of unknown blog = %v, want ErrNotFound", err)
+	}
+
+	if err := blogs.Delete(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blogs.FindByID(ctx, first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindByID after Delete = %v, want ErrNotFound", err)
+	}
+	if err := blogs.Delete(ctx, first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryCommentStore(t *testing.T) {
+	ctx := context.Background()
+	comments := NewMemoryStores().Comments
+
+	comment := &models.Comment{User: primitive.NewObjectID(), Content: "First!", Blog: primitive.NewObjectID()}
+	if err := comments.Create(ctx, comment); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := comments.UpdateContent(ctx, comment.ID, "Second thoughts"); err != nil {
+		t.Fatal(err)
+	}
+	found, err := comments.FindByID(ctx, comment.ID)
+	if err != nil || found.Content != "Second thoughts" {
+		t.Fatalf("FindByID = %v, %v, want the updated content", found, err)
+	}
+	if err := comments.UpdateContent(ctx, primitive.NewObjectID(), "x"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateContent of unknown comment = %v, want ErrNotFound", err)
+	}
+
+	listed, err := comments.FindByIDs(ctx, []primitive.ObjectID{primitive.NewObjectID(), comment.ID})
+	if err != nil || len(listed) != 1 || listed[0].ID != comment.ID {
+		t.Errorf("FindByIDs = %v, %v, want only the stored comment", listed, err)
+	}
+
+	if err := comments.Delete(ctx, comment.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := comments.Delete(ctx, comment.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryCommentReplies(t *testing.T) {
+	ctx := context.Background()
+	comments := NewMemoryStores().Comments
+	bid := primitive.NewObjectID()
+
+	// Created newest first, to show that listing orders by date.
+	start := time.Now()
+	var threads []*models.Comment
+	for i := 3; i > 0; i-- {
+		c := &models.Comment{Blog: bid, Content: "thread", Date: start.Add(time.Duration(i) * time.Minute)}
+		if err := comments.Create(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		threads = append([]*models.Comment{c}, threads...)
+	}
+	reply := &models.Comment{Blog: bid, ParentID: threads[0].ID, Depth: 1, Date: start}
+	other := &models.Comment{Blog: primitive.NewObjectID(), Date: start}
+	for _, c := range []*models.Comment{reply, other} {
+		if err := comments.Create(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, total, err := comments.ListReplies(ctx, bid, primitive.NilObjectID, 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(page) != 2 || page[0].ID != threads[1].ID || page[1].ID != threads[2].ID {
+		t.Errorf("ListReplies = %d of %d, want threads 2 and 3 of 3", len(page), total)
+	}
+	if page, total, _ := comments.ListReplies(ctx, bid, threads[0].ID, 0, 5); total != 1 || page[0].ID != reply.ID {
+		t.Errorf("ListReplies of the first thread = %d of %d, want the reply", len(page), total)
+	}
+
+	found, err := comments.FindReplies(ctx, []primitive.ObjectID{threads[0].ID, threads[1].ID})
+	if err != nil || len(found) != 1 || found[0].ID != reply.ID {
+		t.Errorf("FindReplies = %v, %v, want only the reply", found, err)
+	}
+}
@@ -0,0 +1,622 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func NewMongoStores(db *mongo.Database) *Stores {
+	return &Stores{
+		Users:    &mongoUserStore{col: db.Collection("users")},
+		Blogs:    &mongoBlogStore{col: db.Collection("blogs")},
+		Comments: &mongoCommentStore{col: db.Collection("comments")},
+
+		RefreshTokens: &mongoRefreshTokenStore{col: db.Collection("refreshTokens")},
+		Revocations:   &mongoRevocationStore{col: db.Collection("revokedTokens")},
+		OneTimeTokens: &mongoOneTimeTokenStore{col: db.Collection("oneTimeTokens")},
+		APITokens:     &mongoAPITokenStore{col: db.Collection("apiTokens")},
+
+		Tx: &mongoTransactor{client: db.Client()},
+		DB: mongoPinger{client: db.Client()},
+	}
+}
+
+type mongoPinger struct {
+	client *mongo.Client
+}
+
+func (p mongoPinger) Ping(ctx context.Context) error {
+	return p.client.Ping(ctx, readpref.Primary())
+}
+
+func findOne[T any](ctx context.Context, col *mongo.Collection, filter bson.M) (*T, error) {
+	var doc T
+	err := col.FindOne(ctx, filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func findAll[T any](ctx context.Context, col *mongo.Collection, filter bson.M) ([]T, error) {
+	cursor, err := col.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := make([]T, 0)
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func updateOne(ctx context.Context, col *mongo.Collection, filter, update bson.M) error {
+	result, err := col.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func deleteOne(ctx context.Context, col *mongo.Collection, id primitive.ObjectID) error {
+	result, err := col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type mongoUserStore struct {
+	col *mongo.Collection
+}
+
+func (s *mongoUserStore) Create(ctx context.Context, user *models.User) error {
+	result, err := s.col.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	user.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoUserStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return findOne[models.User](ctx, s.col, bson.M{"_id": id})
+}
+
+func (s *mongoUserStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return findOne[models.User](ctx, s.col, bson.M{"email": email})
+}
+
+func (s *mongoUserStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error) {
+	return findAll[models.User](ctx, s.col, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (s *mongoUserStore) UpdatePassword(ctx context.Context, id primitive.ObjectID, hash string) error {
+	return updateOne(ctx, s.col, bson.M{"_id": id}, bson.M{"$set": bson.M{"password": hash}})
+}
+
+func (s *mongoUserStore) SetEmailVerified(ctx context.Context, id primitive.ObjectID) error {
+	return updateOne(ctx, s.col, bson.M{"_id": id}, bson.M{"$set": bson.M{"emailVerified": true}})
+}
+
+func (s *mongoUserStore) SetRole(ctx context.Context, id primitive.ObjectID, role string) error {
+	return updateOne(ctx, s.col, bson.M{"_id": id}, bson.M{"$set": bson.M{"role": role}})
+}
+
+func (s *mongoUserStore) SetTwoFactor(ctx context.Context, id primitive.ObjectID, twoFactor models.TwoFactor) error {
+	return updateOne(ctx, s.col, bson.M{"_id": id}, bson.M{"$set": bson.M{"twoFactor": twoFactor}})
+}
+
+func (s *mongoUserStore) UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) error {
+	filter := bson.M{
+		"_id":               id,
+		"twoFactor.enabled": true,
+		"$or": bson.A{
+			bson.M{"twoFactor.lastStep": bson.M{"$exists": false}},
+			bson.M{"twoFactor.lastStep": bson.M{"$lt": step}},
+		},
+	}
+	return updateOne(ctx, s.col, filter, bson.M{"$set": bson.M{"twoFactor.lastStep": step}})
+}
+
+func (s *mongoUserStore) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) error {
+	filter := bson.M{"_id": id, "twoFactor.enabled": true, "twoFactor.recoveryCodes": hash}
+	return updateOne(ctx, s.col, filter, bson.M{"$pull": bson.M{"twoFactor.recoveryCodes": hash}})
+}
+
+func (s *mongoUserStore) AddBlog(ctx context.Context, uid, bid primitive.ObjectID) error {
+	return updateOne(ctx, s.col, bson.M{"_id": uid}, bson.M{"$push": bson.M{"blogs": bid}})
+}
+
+func (s *mongoUserStore) RemoveBlog(ctx context.Context, uid, bid primitive.ObjectID) error {
+	_, err := s.col.UpdateOne(ctx, bson.M{"_id": uid}, bson.M{"$pull": bson.M{"blogs": bid}})
+	return err
+}
+
+type mongoBlogStore struct {
+	col *mongo.Collection
+}
+
+func (s *mongoBlogStore) Create(ctx context.Context, blog *models.Blog) error {
+	result, err := s.col.InsertOne(ctx, blog)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	blog.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoBlogStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Blog, error) {
+	return findOne[models.Blog](ctx, s.col, bson.M{"_id": id})
+}
+
+// publishedFilter also matches blogs stored before the status field existed.
+var publishedFilter = bson.M{"status": bson.M{"$in": bson.A{models.StatusPublished, nil}}}
+
+func (s *mongoBlogStore) FindBySlug(ctx context.Context, slug string) (*models.Blog, error) {
+	return findOne[models.Blog](ctx, s.col, bson.M{"$or": bson.A{bson.M{"slug": slug}, bson.M{"slugHistory": slug}}})
+}
+
+func (s *mongoBlogStore) SlugInUse(ctx context.Context, slug string, except primitive.ObjectID) (bool, error) {
+	count, err := s.col.CountDocuments(ctx, bson.M{
+		"_id": bson.M{"$ne": except},
+		"$or": bson.A{bson.M{"slug": slug}, bson.M{"slugHistory": slug}},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *mongoBlogStore) List(ctx context.Context, query BlogQuery) (*BlogPage, error) {
+	sort, c := query.sort(), query.Cursor
+	backward := c != nil && c.Backward
+
+	pipeline := bson.A{
+		bson.M{"$match": blogQueryFilter(query)},
+		bson.M{"$addFields": bson.M{"commentCount": bson.M{"$size": bson.M{"$ifNull": bson.A{"$comments", bson.A{}}}}}},
+	}
+	if c != nil {
+		pipeline = append(pipeline, bson.M{"$match": cursorFilter(c)})
+	}
+	pipeline = append(pipeline, bson.M{"$sort": sortSpec(sort, backward)})
+	if query.Limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": query.Limit + 1})
+	}
+	pipeline = append(pipeline, bson.M{"$project": bson.M{"commentCount": 0}})
+
+	cursor, err := s.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	blogs := make([]models.Blog, 0)
+	if err := cursor.All(ctx, &blogs); err != nil {
+		return nil, err
+	}
+	return pageFrom(query, blogs), nil
+}
+
+func (s *mongoBlogStore) Count(ctx context.Context, query BlogQuery) (int64, error) {
+	return s.col.CountDocuments(ctx, blogQueryFilter(query))
+}
+
+func blogQueryFilter(query BlogQuery) bson.M {
+	visible := publishedFilter
+	if query.IncludeUnpublished {
+		visible = bson.M{}
+	} else if !query.VisibleTo.IsZero() {
+		visible = bson.M{"$or": bson.A{publishedFilter, bson.M{"author": query.VisibleTo}}}
+	}
+
+	conditions := bson.A{visible}
+	if query.Tag != "" {
+		conditions = append(conditions, bson.M{"tags": query.Tag})
+	}
+	if !query.Author.IsZero() {
+		conditions = append(conditions, bson.M{"author": query.Author})
+	}
+	if !query.CreatedFrom.IsZero() {
+		conditions = append(conditions, bson.M{"_id": bson.M{"$gte": primitive.NewObjectIDFromTimestamp(query.CreatedFrom)}})
+	}
+	if !query.CreatedTo.IsZero() {
+		conditions = append(conditions, bson.M{"_id": bson.M{"$lt": primitive.NewObjectIDFromTimestamp(query.CreatedTo)}})
+	}
+	return bson.M{"$and": conditions}
+}
+
+// sortSpec orders by the sort's key with _id as tie-breaker. Backward pages
+// are fetched in reverse and flipped back by pageFrom.
+func sortSpec(sort BlogSort, backward bool) bson.D {
+	dir := func(d int) int {
+		if backward {
+			return -d
+		}
+		return d
+	}
+	switch sort {
+	case SortOldest:
+		return bson.D{{Key: "_id", Value: dir(1)}}
+	case SortTitle:
+		return bson.D{{Key: "title", Value: dir(1)}, {Key: "_id", Value: dir(1)}}
+	case SortMostCommented:
+		return bson.D{{Key: "commentCount", Value: dir(-1)}, {Key: "_id", Value: dir(-1)}}
+	default:
+		return bson.D{{Key: "_id", Value: dir(-1)}}
+	}
+}
+
+// cursorFilter matches the documents strictly after c in its travel direction.
+func cursorFilter(c *Cursor) bson.M {
+	op := func(ascending bool) string {
+		if ascending != c.Backward {
+			return "$gt"
+		}
+		return "$lt"
+	}
+	keyset := func(field string, value any, ascending bool) bson.M {
+		return bson.M{"$or": bson.A{
+			bson.M{field: bson.M{op(ascending): value}},
+			bson.M{field: value, "_id": bson.M{op(ascending): c.ID}},
+		}}
+	}
+
+	switch c.Sort {
+	case SortOldest:
+		return bson.M{"_id": bson.M{op(true): c.ID}}
+	case SortTitle:
+		return keyset("title", c.Title, true)
+	case SortMostCommented:
+		return keyset("commentCount", c.Comments, false)
+	default:
+		return bson.M{"_id": bson.M{op(false): c.ID}}
+	}
+}
+
+func (s *mongoBlogStore) Update(ctx context.Context, blog *models.Blog) error {
+	return updateOne(ctx, s.col, bson.M{"_id": blog.ID}, bson.M{"$set": bson.M{
+		"title":       blog.Title,
+		"slug":        blog.Slug,
+		"slugHistory": blog.SlugHistory,
+		"description": blog.Description,
+		"article":     blog.Article,
+		"tags":        blog.Tags,
+	}})
+}
+
+func (s *mongoBlogStore) UpdateStatus(ctx context.Context, blog *models.Blog) error {
+	return updateOne(ctx, s.col, bson.M{"_id": blog.ID}, bson.M{"$set": bson.M{
+		"status":      blog.Status,
+		"publishAt":   blog.PublishAt,
+		"publishedAt": blog.PublishedAt,
+	}})
+}
+
+func (s *mongoBlogStore) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.col.UpdateMany(ctx,
+		bson.M{"status": models.StatusScheduled, "publishAt": bson.M{"$lte": now}},
+		bson.A{bson.M{"$set": bson.M{"status": models.StatusPublished, "publishedAt": "$publishAt"}}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (s *mongoBlogStore) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	cursor, err := s.col.Aggregate(ctx, bson.A{
+		bson.M{"$match": publishedFilter},
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := make([]models.TagCount, 0)
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (s *mongoBlogStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, s.col, id)
+}
+
+func (s *mongoBlogStore) AddComment(ctx context.Context, bid, cid primitive.ObjectID) error {
+	return updateOne(ctx, s.col, bson.M{"_id": bid}, bson.M{"$push": bson.M{"comments": cid}})
+}
+
+func (s *mongoBlogStore) RemoveComment(ctx context.Context, cid primitive.ObjectID) error {
+	_, err := s.col.UpdateOne(ctx, bson.M{"comments": cid}, bson.M{"$pull": bson.M{"comments": cid}})
+	return err
+}
+
+type mongoCommentStore struct {
+	col *mongo.Collection
+}
+
+func (s *mongoCommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	result, err := s.col.InsertOne(ctx, comment)
+	if err != nil {
+		return err
+	}
+	comment.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoCommentStore) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	return findOne[models.Comment](ctx, s.col, bson.M{"_id": id})
+}
+
+func (s *mongoCommentStore) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Comment, error) {
+	return findAll[models.Comment](ctx, s.col, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (s *mongoCommentStore) UpdateContent(ctx context.Context, id primitive.ObjectID, content string) error {
+	return updateOne(ctx, s.col, bson.M{"_id": id}, bson.M{"$set": bson.M{"content": content}})
+}
+
+func (s *mongoCommentStore) MarkDeleted(ctx context.Context, id primitive.ObjectID) error {
+	return updateOne(ctx, s.col, bson.M{"_id": id}, bson.M{"$set": bson.M{"deleted": true, "content": ""}})
+}
+
+func (s *mongoCommentStore) CountReplies(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	return s.col.CountDocuments(ctx, bson.M{"parentId": id})
+}
+
+func (s *mongoCommentStore) ListReplies(ctx context.Context, bid, parent primitive.ObjectID, offset, limit int) ([]models.Comment, int64, error) {
+	filter := bson.M{"blog": bid, "parentId": parent}
+	if parent.IsZero() {
+		// Top-level comments are stored without a parentId.
+		filter["parentId"] = nil
+	}
+	total, err := s.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := s.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	replies := make([]models.Comment, 0, limit)
+	if err := cursor.All(ctx, &replies); err != nil {
+		return nil, 0, err
+	}
+	return replies, total, nil
+}
+
+func (s *mongoCommentStore) FindReplies(ctx context.Context, parents []primitive.ObjectID) ([]models.Comment, error) {
+	return findAll[models.Comment](ctx, s.col, bson.M{"parentId": bson.M{"$in": parents}})
+}
+
+func (s *mongoCommentStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, s.col, id)
+}
+
+func (s *mongoCommentStore) DeleteByBlog(ctx context.Context, bid primitive.ObjectID) error {
+	_, err := s.col.DeleteMany(ctx, bson.M{"blog": bid})
+	return err
+}
+
+type mongoRefreshTokenStore struct {
+	col *mongo.Collection
+}
+
+func (s *mongoRefreshTokenStore) Create(ctx context.Context, token *models.RefreshToken) error {
+	result, err := s.col.InsertOne(ctx, token)
+	if err != nil {
+		return err
+	}
+	token.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoRefreshTokenStore) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	return findOne[models.RefreshToken](ctx, s.col, bson.M{"tokenHash": hash})
+}
+
+func (s *mongoRefreshTokenStore) Consume(ctx context.Context, id primitive.ObjectID) error {
+	return updateOne(ctx, s.col,
+		bson.M{"_id": id, "used": false, "revoked": false},
+		bson.M{"$set": bson.M{"used": true}})
+}
+
+func (s *mongoRefreshTokenStore) RevokeFamily(ctx context.Context, family primitive.ObjectID) error {
+	_, err := s.col.UpdateMany(ctx, bson.M{"family": family}, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
+
+func (s *mongoRefreshTokenStore) RevokeUser(ctx context.Context, uid primitive.ObjectID) error {
+	_, err := s.col.UpdateMany(ctx, bson.M{"user": uid}, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
+
+type mongoRevocationStore struct {
+	col *mongo.Collection
+}
+
+func (s *mongoRevocationStore) Revoke(ctx context.Context, token *models.RevokedToken) error {
+	_, err := s.col.ReplaceOne(ctx, bson.M{"_id": token.JTI}, token, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (s *mongoRevocationStore) RevokeUser(ctx context.Context, uid primitive.ObjectID, cutoff, expiresAt time.Time) error {
+	return s.Revoke(ctx, &models.RevokedToken{
+		JTI:          userRevocationKey(uid),
+		User:         uid,
+		IssuedBefore: cutoff,
+		ExpiresAt:    expiresAt,
+	})
+}
+
+func (s *mongoRevocationStore) IsRevoked(ctx context.Context, jti string, uid primitive.ObjectID, issuedAt time.Time) (bool, error) {
+	count, err := s.col.CountDocuments(ctx, bson.M{
+		"$or": []bson.M{
+			{"_id": jti},
+			{"_id": userRevocationKey(uid), "issuedBefore": bson.M{"$gte": issuedAt}},
+		},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+type mongoOneTimeTokenStore struct {
+	col *mongo.Collection
+}
+
+func (s *mongoOneTimeTokenStore) Create(ctx context.Context, token *models.OneTimeToken) error {
+	result, err := s.col.InsertOne(ctx, token)
+	if err != nil {
+		return err
+	}
+	token.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoOneTimeTokenStore) Consume(ctx context.Context, purpose, hash string) (*models.OneTimeToken, error) {
+	var token models.OneTimeToken
+	err := s.col.FindOneAndUpdate(ctx,
+		bson.M{"purpose": purpose, "tokenHash": hash, "used": false, "expiresAt": bson.M{"$gt": time.Now()}},
+		bson.M{"$set": bson.M{"used": true}},
+	).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *mongoOneTimeTokenStore) Invalidate(ctx context.Context, uid primitive.ObjectID, purpose string) error {
+	_, err := s.col.UpdateMany(ctx, bson.M{"user": uid, "purpose": purpose, "used": false}, bson.M{"$set": bson.M{"used": true}})
+	return err
+}
+
+func (s *mongoOneTimeTokenStore) Latest(ctx context.Context, uid primitive.ObjectID, purpose string) (*models.OneTimeToken, error) {
+	var token models.OneTimeToken
+	err := s.col.FindOne(ctx,
+		bson.M{"user": uid, "purpose": purpose},
+		options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: -1}}),
+	).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+type mongoAPITokenStore struct {
+	col *mongo.Collection
+}
+
+// unexpired matches tokens without an expiry or with one still ahead; the TTL
+// index only removes expired tokens eventually.
+func unexpired(filter bson.M) bson.M {
+	filter["$or"] = []bson.M{
+		{"expiresAt": bson.M{"$exists": false}},
+		{"expiresAt": bson.M{"$gt": time.Now()}},
+	}
+	return filter
+}
+
+func (s *mongoAPITokenStore) Create(ctx context.Context, token *models.APIToken) error {
+	result, err := s.col.InsertOne(ctx, token)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	token.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (s *mongoAPITokenStore) FindByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	return findOne[models.APIToken](ctx, s.col, unexpired(bson.M{"tokenHash": hash}))
+}
+
+func (s *mongoAPITokenStore) ListByUser(ctx context.Context, uid primitive.ObjectID) ([]models.APIToken, error) {
+	cursor, err := s.col.Find(ctx, unexpired(bson.M{"user": uid}), options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []models.APIToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *mongoAPITokenStore) CountByUser(ctx context.Context, uid primitive.ObjectID) (int64, error) {
+	return s.col.CountDocuments(ctx, unexpired(bson.M{"user": uid}))
+}
+
+func (s *mongoAPITokenStore) MarkUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := s.col.UpdateByID(ctx, id, bson.M{"$max": bson.M{"lastUsedAt": at}})
+	return err
+}
+
+func (s *mongoAPITokenStore) Delete(ctx context.Context, id, uid primitive.ObjectID) error {
+	result, err := s.col.DeleteOne(ctx, bson.M{"_id": id, "user": uid})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoAPITokenStore) DeleteByUser(ctx context.Context, uid primitive.ObjectID) error {
+	_, err := s.col.DeleteMany(ctx, bson.M{"user": uid})
+	return err
+}
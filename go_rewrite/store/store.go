@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound is returned by every store when the requested document does not exist.
+var ErrNotFound = errors.New("store: document not found")
+
+// ErrDuplicate is returned when a write would break a unique constraint, such
+// as a second user with the same email.
+var ErrDuplicate = errors.New("store: duplicate document")
+
+func userRevocationKey(uid primitive.ObjectID) string {
+	return "user:" + uid.Hex()
+}
+
+type UserStore interface {
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.User, error)
+	UpdatePassword(ctx context.Context, id primitive.ObjectID, hash string) error
+	SetEmailVerified(ctx context.Context, id primitive.ObjectID) error
+	SetRole(ctx context.Context, id primitive.ObjectID, role string) error
+	// SetTwoFactor replaces the user's authenticator; the zero value removes it.
+	SetTwoFactor(ctx context.Context, id primitive.ObjectID, twoFactor models.TwoFactor) error
+	// UseTOTPStep records step as the user's last accepted one. It returns
+	// ErrNotFound when 2FA is off or a code of that or a later step was already
+	// accepted, so concurrent logins cannot both use one code.
+	UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) error
+	// UseRecoveryCode removes the recovery code with hash, or returns
+	// ErrNotFound when the user has no such code.
+	UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) error
+	AddBlog(ctx context.Context, uid, bid primitive.ObjectID) error
+	RemoveBlog(ctx context.Context, uid, bid primitive.ObjectID) error
+}
+
+type BlogStore interface {
+	Create(ctx context.Context, blog *models.Blog) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Blog, error)
+	// FindBySlug matches both current and previous slugs; callers compare
+	// against blog.Slug to detect a renamed blog.
+	FindBySlug(ctx context.Context, slug string) (*models.Blog, error)
+	// SlugInUse reports whether any blog other than except has ever used slug.
+	SlugInUse(ctx context.Context, slug string, except primitive.ObjectID) (bool, error)
+	List(ctx context.Context, query BlogQuery) (*BlogPage, error)
+	// Count returns how many blogs match query, ignoring its cursor and limit.
+	Count(ctx context.Context, query BlogQuery) (int64, error)
+	// Create and Update return ErrDuplicate when another blog already has
+	// blog.Slug, which SlugInUse alone cannot rule out under concurrent writes.
+	//
+	// Update overwrites the editable fields and slugs of the blog identified by blog.ID.
+	Update(ctx context.Context, blog *models.Blog) error
+	// UpdateStatus overwrites the status and publishing timestamps of blog.
+	UpdateStatus(ctx context.Context, blog *models.Blog) error
+	// PublishDue publishes every scheduled blog whose publishAt is not after now.
+	PublishDue(ctx context.Context, now time.Time) (int64, error)
+	// TagCounts counts published blogs per tag, most used first.
+	TagCounts(ctx context.Context) ([]models.TagCount, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	AddComment(ctx context.Context, bid, cid primitive.ObjectID) error
+	RemoveComment(ctx context.Context, cid primitive.ObjectID) error
+}
+
+type CommentStore interface {
+	Create(ctx context.Context, comment *models.Comment) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Comment, error)
+	UpdateContent(ctx context.Context, id primitive.ObjectID, content string) error
+	// MarkDeleted blanks a comment that still has replies, keeping its place
+	// in the thread.
+	MarkDeleted(ctx context.Context, id primitive.ObjectID) error
+	CountReplies(ctx context.Context, id primitive.ObjectID) (int64, error)
+	// ListReplies returns up to limit replies to parent on blog from offset
+	// on, oldest first, and how many there are in all. The zero parent lists
+	// the top-level comments.
+	ListReplies(ctx context.Context, bid, parent primitive.ObjectID, offset, limit int) ([]models.Comment, int64, error)
+	// FindReplies returns the direct replies to any of parents.
+	FindReplies(ctx context.Context, parents []primitive.ObjectID) ([]models.Comment, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	DeleteByBlog(ctx context.Context, bid primitive.ObjectID) error
+}
+
+type RefreshTokenStore interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// Consume marks an unused, unrevoked token as used. It returns ErrNotFound
+	// when the token was already consumed, so concurrent refreshes cannot both win.
+	Consume(ctx context.Context, id primitive.ObjectID) error
+	RevokeFamily(ctx context.Context, family primitive.ObjectID) error
+	RevokeUser(ctx context.Context, uid primitive.ObjectID) error
+}
+
+type RevocationStore interface {
+	Revoke(ctx context.Context, token *models.RevokedToken) error
+	// RevokeUser rejects every access token of uid issued at or before cutoff.
+	// The entry can be dropped at expiresAt, once all such tokens have expired.
+	RevokeUser(ctx context.Context, uid primitive.ObjectID, cutoff, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string, uid primitive.ObjectID, issuedAt time.Time) (bool, error)
+}
+
+type OneTimeTokenStore interface {
+	Create(ctx context.Context, token *models.OneTimeToken) error
+	// Consume atomically marks the matching unused, unexpired token as used and
+	// returns it, or returns ErrNotFound.
+	Consume(ctx context.Context, purpose, hash string) (*models.OneTimeToken, error)
+	// Invalidate marks every outstanding token of uid for purpose as used.
+	Invalidate(ctx context.Context, uid primitive.ObjectID, purpose string) error
+	// Latest returns the most recently created token of uid for purpose.
+	Latest(ctx context.Context, uid primitive.ObjectID, purpose string) (*models.OneTimeToken, error)
+}
+
+type APITokenStore interface {
+	Create(ctx context.Context, token *models.APIToken) error
+	// FindByHash returns the unexpired token with hash.
+	FindByHash(ctx context.Context, hash string) (*models.APIToken, error)
+	// ListByUser returns the unexpired tokens of uid, newest first.
+	ListByUser(ctx context.Context, uid primitive.ObjectID) ([]models.APIToken, error)
+	CountByUser(ctx context.Context, uid primitive.ObjectID) (int64, error)
+	MarkUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// Delete revokes the token id of uid, or returns ErrNotFound when uid has
+	// no such token.
+	Delete(ctx context.Context, id, uid primitive.ObjectID) error
+	DeleteByUser(ctx context.Context, uid primitive.ObjectID) error
+}
+
+// Pinger reports whether the database behind the stores can be reached.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Stores groups the repositories the handlers depend on.
+type Stores struct {
+	Users    UserStore
+	Blogs    BlogStore
+	Comments CommentStore
+
+	RefreshTokens RefreshTokenStore
+	Revocations   RevocationStore
+	OneTimeTokens OneTimeTokenStore
+	APITokens     APITokenStore
+
+	Tx Transactor
+	DB Pinger
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Undo collects the compensating steps of a multi-document write. They only
+// run when the write fails and the backend could not wrap it in a real
+// transaction.
+type Undo struct {
+	steps []func(ctx context.Context) error
+}
+
+// Add registers step to revert the write that just succeeded.
+func (u *Undo) Add(step func(ctx context.Context) error) {
+	u.steps = append(u.steps, step)
+}
+
+// Transactor runs writes that span several documents or collections so that
+// they apply together or not at all.
+type Transactor interface {
+	// Run calls fn, passing the context every store call inside it must use.
+	// fn may be retried, so it must not have side effects outside the stores.
+	Run(ctx context.Context, fn func(ctx context.Context, undo *Undo) error) error
+}
+
+// compensationTimeout bounds the undo steps, which run detached from the
+// request's context since that may be what failed.
+const compensationTimeout = 30 * time.Second
+
+// compensate calls fn and, if it fails, runs its undo steps newest first.
+// Errors from the undo steps are logged and joined to fn's error.
+func compensate(ctx context.Context, fn func(ctx context.Context, undo *Undo) error) error {
+	var undo Undo
+	err := fn(ctx, &undo)
+	if err == nil {
+		return nil
+	}
+
+	undoCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), compensationTimeout)
+	defer cancel()
+	for i := len(undo.steps) - 1; i >= 0; i-- {
+		if undoErr := undo.steps[i](undoCtx); undoErr != nil {
+			slog.Error("compensating a failed write failed", "error", undoErr)
+			err = errors.Join(err, undoErr)
+		}
+	}
+	return err
+}
+
+// compensatingTransactor is used by backends without transactions.
+type compensatingTransactor struct{}
+
+func (compensatingTransactor) Run(ctx context.Context, fn func(ctx context.Context, undo *Undo) error) error {
+	return compensate(ctx, fn)
+}
+
+// mongoTransactor uses MongoDB transactions on replica sets and sharded
+// clusters, and falls back to compensation on standalone servers, which do
+// not support them.
+type mongoTransactor struct {
+	client *mongo.Client
+
+	mu      sync.Mutex
+	checked bool
+	native  bool
+}
+
+func (t *mongoTransactor) Run(ctx context.Context, fn func(ctx context.Context, undo *Undo) error) error {
+	if !t.supportsTransactions(ctx) {
+		return compensate(ctx, fn)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx, &Undo{})
+	})
+	return err
+}
+
+// supportsTransactions asks the server about its topology once. A failed
+// check is retried on the next call and compensates in the meantime.
+func (t *mongoTransactor) supportsTransactions(ctx context.Context) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.checked {
+		return t.native
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := t.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		slog.Warn("checking for transaction support failed", "error", err)
+		return false
+	}
+
+	t.checked = true
+	t.native = hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !t.native {
+		slog.Warn("MongoDB is a standalone server; multi-document writes fall back to compensation")
+	}
+	return t.native
+}